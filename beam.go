@@ -0,0 +1,139 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Beam is a hypothesis of a beam search
+type Beam struct {
+	Mix Mix
+	// Text is the decoded text, it is cut at the stop conditions
+	Text *Text
+	// Symbols are the decoded symbols including those of a stop
+	Symbols []byte
+	// Score is the sum of the log probabilities of the symbols
+	Score float64
+	// Done is true if the text is finished before the length of the search
+	Done bool
+}
+
+// Normalized is the score normalized by the length of the beam raised to alpha
+func (b Beam) Normalized(alpha float64) float64 {
+	if len(b.Symbols) == 0 {
+		return b.Score
+	}
+	return b.Score / math.Pow(float64(len(b.Symbols)), alpha)
+}
+
+// BeamSearch is a beam search decoder
+type BeamSearch struct {
+	// Width is the number of beams kept
+	Width int
+	// Length is the number of symbols decoded
+	Length int
+	// Alpha is the length normalization exponent
+	Alpha float64
+	// Duplicate is the number of trailing symbols compared to prune duplicate beams, 0 compares all of the symbols
+	Duplicate int
+}
+
+// FlagBeamSearch is the beam search selected by the flags
+func FlagBeamSearch(length int) BeamSearch {
+	return BeamSearch{
+		Width:     *FlagBeam,
+		Length:    length,
+		Alpha:     *FlagAlpha,
+		Duplicate: *FlagDuplicate,
+	}
+}
+
+// key is the key of a beam used to find duplicates
+func (b BeamSearch) key(symbols []byte) string {
+	if b.Duplicate > 0 && len(symbols) > b.Duplicate {
+		symbols = symbols[len(symbols)-b.Duplicate:]
+	}
+	return string(symbols)
+}
+
+// Search decodes the most likely continuations of m into copies of text, the penalty and the constraint of the text
+// are applied and a beam is done when its text is finished, the beams are sorted by normalized score
+func (b BeamSearch) Search(m Mix, distribution func(m Mix) []float32, text *Text) []Beam {
+	type Candidate struct {
+		Beam   int
+		Symbol byte
+		Score  float64
+	}
+	beams, done := []Beam{{Mix: m.Copy(), Text: text.Copy()}}, []Beam{}
+	seen := make(map[string]bool)
+	for range b.Length {
+		if len(beams) == 0 {
+			break
+		}
+		candidates := make([]Candidate, 0, len(beams)*b.Width)
+		for i, beam := range beams {
+			d := beam.Text.Apply(distribution(beam.Mix))
+			order := make([]int, len(d))
+			for j := range order {
+				order[j] = j
+			}
+			sort.SliceStable(order, func(x, y int) bool {
+				return d[order[x]] > d[order[y]]
+			})
+			for _, j := range order[:min(b.Width, len(order))] {
+				if d[j] <= 0 {
+					break
+				}
+				candidates = append(candidates, Candidate{i, byte(j), beam.Score + math.Log(float64(d[j]))})
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].Score > candidates[j].Score
+		})
+		next, selected := make([]Beam, 0, b.Width), 0
+		clear(seen)
+		for _, candidate := range candidates {
+			if selected == b.Width {
+				break
+			}
+			parent := beams[candidate.Beam]
+			symbols := append(append(make([]byte, 0, len(parent.Symbols)+1), parent.Symbols...), candidate.Symbol)
+			key := b.key(symbols)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			selected++
+			beam := Beam{Text: parent.Text.Copy(), Symbols: symbols, Score: candidate.Score}
+			if beam.Text.Add(candidate.Symbol) {
+				if beam.Text.Finish != FinishDead {
+					beam.Done = true
+					done = append(done, beam)
+				}
+				continue
+			}
+			beam.Mix = parent.Mix.Copy()
+			beam.Mix.Add(candidate.Symbol)
+			next = append(next, beam)
+		}
+		beams = next
+	}
+	beams = append(done, beams...)
+	sort.SliceStable(beams, func(i, j int) bool {
+		return beams[i].Normalized(b.Alpha) > beams[j].Normalized(b.Alpha)
+	})
+	return beams
+}
+
+// Print prints the best count beams with their normalized scores
+func (b BeamSearch) Print(beams []Beam, count int) {
+	for _, beam := range beams[:min(count, len(beams))] {
+		fmt.Println(beam.Normalized(b.Alpha))
+		beam.Text.Print()
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// chain is a markov chain mixer for testing decoders
+type chain struct {
+	Last byte
+}
+
+func (c chain) Copy() Mix {
+	return &c
+}
+
+func (c *chain) Add(s byte) {
+	c.Last = s
+}
+
+func (c chain) Mix() []float32 {
+	switch c.Last {
+	case 0:
+		return []float32{.3, .3, .4}
+	case 1:
+		return []float32{0, 1, 0}
+	}
+	return []float32{.6, .4, 0}
+}
+
+func TestBeamSearch(t *testing.T) {
+	next := func(m Mix) []float32 {
+		return m.Mix()
+	}
+	greedy := BeamSearch{Width: 1, Length: 2, Alpha: 1}.Search(&chain{Last: 2}, next, NewText(Stop{}, nil))
+	if s := greedy[0].Symbols; len(s) != 2 || s[0] != 0 {
+		t.Fatalf("greedy %v", s)
+	}
+	beams := BeamSearch{Width: 2, Length: 2, Alpha: 1}.Search(&chain{Last: 2}, next, NewText(Stop{}, nil))
+	if s := beams[0].Symbols; len(s) != 2 || s[0] != 1 || s[1] != 1 {
+		t.Fatalf("beam %v", s)
+	}
+	if score := beams[0].Score; math.Abs(score-math.Log(.4)) > 1e-6 {
+		t.Fatalf("%f != %f", score, math.Log(.4))
+	}
+	if len(beams) != 2 || beams[0].Normalized(1) < beams[1].Normalized(1) {
+		t.Fatal("the beams are not sorted")
+	}
+
+	if beams[0].Done || beams[0].Text.Finish != FinishLength || beams[0].Text.String() != "\x01\x01" {
+		t.Fatalf("%v %q", beams[0].Text.Finish, beams[0].Text.String())
+	}
+
+	stop := NewText(Stop{Strings: []string{"\x00"}}, nil)
+	for _, test := range []struct {
+		Alpha   float64
+		Symbols string
+		Finish  Finish
+	}{
+		{0, "\x00", FinishStop},
+		{1, "\x01\x01\x01", FinishLength},
+	} {
+		beams := BeamSearch{Width: 2, Length: 3, Alpha: test.Alpha}.Search(&chain{Last: 2}, next, stop)
+		if string(beams[0].Symbols) != test.Symbols || beams[0].Text.Finish != test.Finish {
+			t.Fatalf("%f %q %v", test.Alpha, beams[0].Symbols, beams[0].Text.Finish)
+		}
+		if beams[0].Done != (test.Finish == FinishStop) || len(beams) != 2 {
+			t.Fatalf("%f %v %d", test.Alpha, beams[0].Done, len(beams))
+		}
+	}
+	if len(stop.Symbols) != 0 {
+		t.Fatal("the text of the search was changed")
+	}
+
+	pruned := BeamSearch{Width: 3, Length: 3, Alpha: 1, Duplicate: 1}.Search(&chain{Last: 2}, next, NewText(Stop{}, nil))
+	seen := make(map[byte]bool)
+	for _, beam := range pruned {
+		last := beam.Symbols[len(beam.Symbols)-1]
+		if seen[last] {
+			t.Fatalf("duplicate beam %v", beam.Symbols)
+		}
+		seen[last] = true
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func parseInts(s string) ([]int, error) {
+	var values []int
+	for _, field := range strings.Split(s, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func parseFloats(s string) ([]float64, error) {
+	var values []float64
+	for _, field := range strings.Split(s, ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// NewMixerConfig makes a mixer configuration from comma separated windows, rates and half-lives,
+// empty strings and a negative order select the defaults
+func NewMixerConfig(windows string, order int, rates, halfLives string) (MixerConfig, error) {
+	config := DefaultMixerConfig()
+	var err error
+	if windows != "" {
+		config.Windows, err = parseInts(windows)
+		if err != nil {
+			return config, err
+		}
+	}
+	if order >= 0 {
+		config.Order = order
+	}
+	if rates != "" {
+		config.Rates, err = parseInts(rates)
+		if err != nil {
+			return config, err
+		}
+	}
+	if halfLives != "" {
+		config.HalfLives, err = parseFloats(halfLives)
+		if err != nil {
+			return config, err
+		}
+	}
+	return config, config.Check()
+}
+
+// Meta is the metadata of a database
+type Meta struct {
+	Mixer MixerConfig
+	// Decayed is true if the database was built with the decayed mixer
+	Decayed bool
+	// Head is the file of the learned attention head of the mixer
+	Head string
+	// Stack are the names of the mixers fused by a stack
+	Stack []string
+	// Concat concatenates the outputs of the stack instead of fusing them, the vectors of the database
+	// are as wide as the concatenated outputs
+	Concat bool
+}
+
+// NewFilteredWithMeta makes a new filtered counter for the metadata of a database
+func NewFilteredWithMeta(meta Meta) *Filtered {
+	m := NewFilteredWithConfig(meta.Mixer)
+	if meta.Head != "" {
+		head, err := LoadHead(meta.Head)
+		if err != nil {
+			panic(err)
+		}
+		m.Head = head
+	}
+	return m
+}
+
+// SaveMeta saves the metadata of the database name
+func SaveMeta(name string, meta Meta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name+".json", data, 0640)
+}
+
+// LoadMeta loads the metadata of the database name, the defaults are returned if there is no metadata
+func LoadMeta(name string) (Meta, error) {
+	meta := Meta{
+		Mixer: DefaultMixerConfig(),
+	}
+	data, err := os.ReadFile(name + ".json")
+	if errors.Is(err, os.ErrNotExist) {
+		return meta, nil
+	} else if err != nil {
+		return meta, err
+	}
+	err = json.Unmarshal(data, &meta)
+	if err != nil {
+		return meta, err
+	}
+	return meta, meta.Mixer.Check()
+}
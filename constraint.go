@@ -0,0 +1,384 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp/syntax"
+	"slices"
+	"strings"
+)
+
+const (
+	// Backtracks is the maximum number of symbols replaced when a constraint dead ends
+	Backtracks = 1024
+	// DFAStates is the maximum number of states of a DFA
+	DFAStates = 4096
+	// unknown is a transition that has not been computed
+	unknown = -2
+	// dead is the state that can't match
+	dead = -1
+)
+
+// DFA is a deterministic automaton of a regular expression over the rune alphabet, it is built when it is compiled
+// so it is read only and can be shared, the expression matches the whole text and empty width assertions are ignored
+type DFA struct {
+	// Regexp is the parsed expression
+	Regexp   *syntax.Regexp
+	Prog     *syntax.Prog
+	Alphabet []rune
+	Start    int
+	Sets     [][]uint32
+	States   map[string]int
+	Next     [][]int
+	Accept   []bool
+	Live     []int8
+	overflow bool
+}
+
+// NewDFA compiles a regular expression, symbols are runes if reverse is nil, an expression with more than DFAStates
+// states is an error
+func NewDFA(expression string, reverse map[byte]rune) (*DFA, error) {
+	re, err := syntax.Parse(expression, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	alphabet := make([]rune, 256)
+	for i := range alphabet {
+		alphabet[i] = rune(i)
+		if reverse != nil {
+			r, ok := reverse[byte(i)]
+			if !ok {
+				r = -1
+			}
+			alphabet[i] = r
+		}
+	}
+	return newDFA(re, alphabet)
+}
+
+// newDFA compiles a parsed regular expression over an alphabet
+func newDFA(re *syntax.Regexp, alphabet []rune) (*DFA, error) {
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	d := &DFA{
+		Regexp:   re,
+		Prog:     prog,
+		Alphabet: alphabet,
+		States:   make(map[string]int),
+	}
+	d.Start = d.state([]uint32{uint32(prog.Start)})
+	d.IsLive(d.Start)
+	if d.overflow {
+		return nil, fmt.Errorf("the expression has more than %d states", DFAStates)
+	}
+	return d, nil
+}
+
+// reverse reverses a regular expression so that it matches the reversed texts
+func reverse(re *syntax.Regexp) *syntax.Regexp {
+	cp := *re
+	cp.Sub = make([]*syntax.Regexp, len(re.Sub))
+	for i, sub := range re.Sub {
+		cp.Sub[i] = reverse(sub)
+	}
+	switch re.Op {
+	case syntax.OpConcat:
+		slices.Reverse(cp.Sub)
+	case syntax.OpLiteral:
+		cp.Rune = slices.Clone(re.Rune)
+		slices.Reverse(cp.Rune)
+	case syntax.OpBeginLine:
+		cp.Op = syntax.OpEndLine
+	case syntax.OpEndLine:
+		cp.Op = syntax.OpBeginLine
+	case syntax.OpBeginText:
+		cp.Op = syntax.OpEndText
+	case syntax.OpEndText:
+		cp.Op = syntax.OpBeginText
+	}
+	return &cp
+}
+
+// Reversed is the DFA of the reversed expression over the same alphabet, it matches the reversed texts
+// and panics if the reversed expression has more than DFAStates states
+func (d *DFA) Reversed() *DFA {
+	reversed, err := newDFA(reverse(d.Regexp), d.Alphabet)
+	if err != nil {
+		panic(err)
+	}
+	return reversed
+}
+
+// state returns the state of the closure of a set of instructions
+func (d *DFA) state(pcs []uint32) int {
+	set, visited, stack := []uint32{}, make(map[uint32]bool), pcs
+	for len(stack) > 0 {
+		pc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[pc] {
+			continue
+		}
+		visited[pc] = true
+		switch inst := d.Prog.Inst[pc]; inst.Op {
+		case syntax.InstAlt, syntax.InstAltMatch:
+			stack = append(stack, inst.Arg, inst.Out)
+		case syntax.InstCapture, syntax.InstNop, syntax.InstEmptyWidth:
+			stack = append(stack, inst.Out)
+		case syntax.InstFail:
+		default:
+			set = append(set, pc)
+		}
+	}
+	if len(set) == 0 {
+		return dead
+	}
+	slices.Sort(set)
+	key := fmt.Sprint(set)
+	if state, ok := d.States[key]; ok {
+		return state
+	}
+	if len(d.Sets) == DFAStates {
+		d.overflow = true
+		return dead
+	}
+	state, accept := len(d.Sets), false
+	for _, pc := range set {
+		accept = accept || d.Prog.Inst[pc].Op == syntax.InstMatch
+	}
+	next := make([]int, len(d.Alphabet))
+	for i := range next {
+		next[i] = unknown
+	}
+	d.States[key] = state
+	d.Sets = append(d.Sets, set)
+	d.Next = append(d.Next, next)
+	d.Accept = append(d.Accept, accept)
+	d.Live = append(d.Live, 0)
+	return state
+}
+
+// Step is the state after a symbol
+func (d *DFA) Step(state int, symbol byte) int {
+	if state == dead {
+		return dead
+	}
+	if next := d.Next[state][symbol]; next != unknown {
+		return next
+	}
+	r, pcs := d.Alphabet[symbol], []uint32{}
+	if r >= 0 {
+		for _, pc := range d.Sets[state] {
+			inst := d.Prog.Inst[pc]
+			match := false
+			switch inst.Op {
+			case syntax.InstRune, syntax.InstRune1:
+				match = inst.MatchRune(r)
+			case syntax.InstRuneAny:
+				match = true
+			case syntax.InstRuneAnyNotNL:
+				match = r != '\n'
+			}
+			if match {
+				pcs = append(pcs, inst.Out)
+			}
+		}
+	}
+	next := dead
+	if len(pcs) > 0 {
+		next = d.state(pcs)
+	}
+	d.Next[state][symbol] = next
+	return next
+}
+
+// IsLive is true if a match can be reached from the state
+func (d *DFA) IsLive(state int) bool {
+	if state == dead {
+		return false
+	}
+	if d.Live[state] != 0 {
+		return d.Live[state] > 0
+	}
+	reachable, visited := []int{state}, map[int]bool{state: true}
+	for i := 0; i < len(reachable); i++ {
+		for symbol := range d.Alphabet {
+			next := d.Step(reachable[i], byte(symbol))
+			if next != dead && !visited[next] {
+				visited[next] = true
+				reachable = append(reachable, next)
+			}
+		}
+	}
+	live := make(map[int]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, s := range reachable {
+			if live[s] {
+				continue
+			}
+			if d.Accept[s] || (d.Live[s] > 0) {
+				live[s], changed = true, true
+				continue
+			}
+			for _, next := range d.Next[s] {
+				if next >= 0 && live[next] {
+					live[s], changed = true, true
+					break
+				}
+			}
+		}
+	}
+	for _, s := range reachable {
+		d.Live[s] = -1
+		if live[s] {
+			d.Live[s] = 1
+		}
+	}
+	return d.Live[state] > 0
+}
+
+// ParseGrammar expands a grammar into a regular expression, each line is a rule
+// "name = expression" where <name> refers to another rule, the first rule is the start
+// and lines starting with # are comments
+func ParseGrammar(grammar string) (string, error) {
+	rules, start := make(map[string]string), ""
+	for _, line := range strings.Split(grammar, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, expression, ok := strings.Cut(line, "=")
+		if !ok {
+			return "", fmt.Errorf("rule %q has no =", line)
+		}
+		name = strings.TrimSpace(name)
+		if _, ok := rules[name]; ok {
+			return "", fmt.Errorf("rule %s is defined twice", name)
+		}
+		rules[name] = strings.TrimSpace(expression)
+		if start == "" {
+			start = name
+		}
+	}
+	if start == "" {
+		return "", fmt.Errorf("grammar has no rules")
+	}
+	var expand func(name string, path []string) (string, error)
+	expand = func(name string, path []string) (string, error) {
+		if slices.Contains(path, name) {
+			return "", fmt.Errorf("rule %s is recursive", name)
+		}
+		expression, ok := rules[name]
+		if !ok {
+			return "", fmt.Errorf("rule %s is not defined", name)
+		}
+		path = append(path, name)
+		output := strings.Builder{}
+		for {
+			begin := strings.Index(expression, "<")
+			if begin < 0 {
+				break
+			}
+			end := strings.Index(expression[begin:], ">")
+			if end < 0 {
+				break
+			}
+			rule, err := expand(expression[begin+1:begin+end], path)
+			if err != nil {
+				return "", err
+			}
+			output.WriteString(expression[:begin])
+			output.WriteString("(?:" + rule + ")")
+			expression = expression[begin+end+1:]
+		}
+		output.WriteString(expression)
+		return output.String(), nil
+	}
+	return expand(start, nil)
+}
+
+// Constraint masks the symbols that can't lead to a match of a DFA
+type Constraint struct {
+	DFA   *DFA
+	State int
+}
+
+// NewConstraint makes a new constraint at the start of a DFA
+func NewConstraint(dfa *DFA) *Constraint {
+	return &Constraint{
+		DFA:   dfa,
+		State: dfa.Start,
+	}
+}
+
+// FlagConstraint is the constraint selected by the flags, nil if there is no constraint
+func FlagConstraint(reverse map[byte]rune) *Constraint {
+	expression := *FlagConstraintExpression
+	if *FlagGrammar != "" {
+		if expression != "" {
+			panic("only one of constraint and grammar can be set")
+		}
+		grammar, err := os.ReadFile(*FlagGrammar)
+		if err != nil {
+			panic(err)
+		}
+		expression, err = ParseGrammar(string(grammar))
+		if err != nil {
+			panic(err)
+		}
+	}
+	if expression == "" {
+		return nil
+	}
+	dfa, err := NewDFA(expression, reverse)
+	if err != nil {
+		panic(err)
+	}
+	return NewConstraint(dfa)
+}
+
+// Copy copies the constraint, the DFA is shared
+func (c *Constraint) Copy() *Constraint {
+	cp := *c
+	return &cp
+}
+
+// Mask zeros the symbols of a distribution that can't lead to a match returning a new distribution
+func (c *Constraint) Mask(distribution []float32) []float32 {
+	output := make([]float32, len(distribution))
+	for i, v := range distribution {
+		if i < len(c.DFA.Alphabet) && c.DFA.IsLive(c.DFA.Step(c.State, byte(i))) {
+			output[i] = v
+		}
+	}
+	return output
+}
+
+// Add adds a symbol returning false if the symbol can't lead to a match
+func (c *Constraint) Add(symbol byte) bool {
+	next := c.DFA.Step(c.State, symbol)
+	if !c.DFA.IsLive(next) {
+		return false
+	}
+	c.State = next
+	return true
+}
+
+// Complete is true if the text matches and no symbol can extend the match
+func (c *Constraint) Complete() bool {
+	if !c.DFA.Accept[c.State] {
+		return false
+	}
+	for symbol := range c.DFA.Alphabet {
+		if c.DFA.IsLive(c.DFA.Step(c.State, byte(symbol))) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConstraint(t *testing.T) {
+	expression, err := ParseGrammar("# a speaker\r\nspeaker = <name>\\.\r\nname = [[:upper:]]{2,4}\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dfa, err := NewDFA(expression, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewConstraint(dfa)
+	uniform := make([]float32, 128)
+	for i := range uniform {
+		uniform[i] = 1.0 / 128
+	}
+	for _, s := range []byte("HAM") {
+		if d := c.Mask(uniform); d['a'] != 0 || d[s] == 0 {
+			t.Fatalf("%c is not allowed", s)
+		}
+		if !c.Add(s) {
+			t.Fatalf("%c is dead", s)
+		}
+	}
+	if c.Complete() || c.Mask(uniform)['.'] == 0 {
+		t.Fatal("the name should be extended by a period")
+	}
+	if c.Copy().Add('a') {
+		t.Fatal("a lower case letter should be dead")
+	}
+	if !c.Add('.') || !c.Complete() {
+		t.Fatal("the speaker should be complete")
+	}
+
+	dfa, err = NewDFA("a(?:bc)+d", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for text, match := range map[string]bool{"dcbcba": true, "dcba": true, "abcd": false, "dbca": false} {
+		c, matched := NewConstraint(dfa.Reversed()), true
+		for _, s := range []byte(text) {
+			matched = matched && c.Add(s)
+		}
+		if matched = matched && c.DFA.Accept[c.State]; matched != match {
+			t.Fatalf("%q %t", text, match)
+		}
+	}
+
+	if _, err := NewDFA("[ab]*a[ab]{16}", nil); err == nil {
+		t.Fatal("the states of the expression should overflow")
+	}
+
+	for _, grammar := range []string{"a = <a>", "a = <b>", "a b", ""} {
+		if _, err := ParseGrammar(grammar); err == nil {
+			t.Fatalf("%q should not parse", grammar)
+		}
+	}
+
+	// the sampler prefers x which dead ends after two symbols so it has to backtrack
+	dfa, err = NewDFA("(?:xxy|z)+", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	distribution := make([]float32, 128)
+	distribution['x'], distribution['z'] = .9, .1
+	text := NewText(Stop{}, nil)
+	text.Constraint = NewConstraint(dfa)
+	text = NewSampler(0, 0, 1, 9, 1, 1).Generate(NewFiltered(), func(m Mix) []float32 { return distribution }, text)
+	if s := text.String(); len(s) != 9 || s[0] != 'z' || strings.Trim(s, "xz") != "" || strings.Contains(s, "xxx") {
+		t.Fatalf("%q is not backtracked", s)
+	}
+}
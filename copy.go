@@ -0,0 +1,147 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Copier copies the corpus that follows a strong neighbor match instead of searching for every symbol
+type Copier struct {
+	Items []Item
+	// Symbols is the number of symbols in the alphabet
+	Symbols int
+	// Threshold is the similarity of the neighbor that starts a copy
+	Threshold float64
+	// Verify is the similarity of the next record to the mixer vector that continues a copy
+	Verify float64
+	// Searches is the number of full searches
+	Searches int
+	// Copies is the number of copied symbols
+	Copies int
+}
+
+// NewCopier makes a new copier over a vector database
+func NewCopier(items []Item, symbols int, threshold, verify float64) *Copier {
+	return &Copier{
+		Items:     items,
+		Symbols:   symbols,
+		Threshold: threshold,
+		Verify:    verify,
+	}
+}
+
+// FlagCopier is the copier selected by the flags, nil if there is no copying
+func FlagCopier(items []Item, symbols int) *Copier {
+	if *FlagCopy <= 0 {
+		return nil
+	}
+	return NewCopier(items, symbols, *FlagCopy, *FlagVerify)
+}
+
+// follows is the record that follows a record in the corpus or -1 if there is none
+func (c *Copier) follows(index int) int {
+	next := index + 1
+	if index < 0 || next >= len(c.Items) {
+		return -1
+	}
+	a, b := c.Items[index].Source, c.Items[next].Source
+	if a.Document != b.Document || a.Offset+1 != b.Offset {
+		return -1
+	}
+	return next
+}
+
+// Proposer is a mixer that proposes its next symbol, the proposal is generated instead of sampling the distribution
+// if the text allows it
+type Proposer interface {
+	Propose() (byte, bool)
+}
+
+// Copying is a mixer that follows its position in the corpus of a copier
+type Copying struct {
+	Mixer  Mix
+	Copier *Copier
+	// Position is the record of the last symbol or -1 if the last symbol wasn't copied or found
+	Position int
+	// Neighbors are the neighbors the distribution of the next symbol was computed from
+	Neighbors []Neighbor
+	proposal  int
+}
+
+// Copy copies the mixer
+func (c *Copying) Copy() Mix {
+	cp := *c
+	cp.Mixer = c.Mixer.Copy()
+	return &cp
+}
+
+// Propose proposes the symbol of the record that follows the position if the record is similar to the mixer vector
+func (c *Copying) Propose() (byte, bool) {
+	c.proposal = c.Copier.follows(c.Position)
+	if c.proposal < 0 || dot(&c.Copier.Items[c.proposal].Vector, c.Mix()) < c.Copier.Verify {
+		c.proposal = -1
+		return 0, false
+	}
+	return c.Copier.Items[c.proposal].Symbol, true
+}
+
+// Add adds a symbol to the mixer, the position is the proposed record if the symbol was proposed
+// or the neighbor of the symbol if it is similar enough to start a copy
+func (c *Copying) Add(s byte) {
+	position := -1
+	if c.proposal >= 0 && c.Copier.Items[c.proposal].Symbol == s {
+		position = c.proposal
+		c.Copier.Copies++
+	} else {
+		for _, neighbor := range c.Neighbors {
+			if neighbor.Symbol == s {
+				if neighbor.Similarity >= c.Copier.Threshold {
+					position = neighbor.Index
+				}
+				break
+			}
+		}
+	}
+	c.Position, c.Neighbors, c.proposal = position, nil, -1
+	c.Mixer.Add(s)
+}
+
+// Mix is the output of the mixer
+func (c *Copying) Mix() []float32 {
+	return c.Mixer.Mix()
+}
+
+// Confidence is the confidence of the mixer
+func (c *Copying) Confidence() float64 {
+	return confidence(c.Mixer)
+}
+
+// Generate samples symbols into text until the length or a stop condition is reached, while copying the next symbol is
+// the symbol of the next corpus record if the record is similar to the mixer vector and the text allows it,
+// otherwise the symbol is sampled from distribution, which returns the distribution of the machine and the neighbors
+// it was computed from, and copying starts if the neighbor of the sampled symbol is similar
+func (c *Copier) Generate(s *Sampler, m Mix, distribution func(m Mix) ([]float32, []Neighbor), text *Text) *Text {
+	next := func(m Mix) []float32 {
+		copying := m.(*Copying)
+		d, neighbors := distribution(copying.Mixer)
+		copying.Neighbors = neighbors
+		c.Searches++
+		return d
+	}
+	return s.Generate(&Copying{Mixer: m, Copier: c, Position: -1, proposal: -1}, next, text)
+}
+
+// Search is the knn distribution of the copier database and the neighbors it was computed from
+func (c *Copier) Search(m Mix) ([]float32, []Neighbor) {
+	neighbors := Search(c.Items, m.Mix(), KNNNeighbors)
+	return KNN(neighbors, c.Symbols), neighbors
+}
+
+// Print reports the number of searches and copies on standard error
+func (c *Copier) Print() {
+	fmt.Fprintln(os.Stderr, "searches", c.Searches, "copies", c.Copies)
+}
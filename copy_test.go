@@ -0,0 +1,64 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCopier(t *testing.T) {
+	corpus := strings.Repeat("shall I compare thee to a summer's day? ", 4)
+	m := NewFiltered()
+	m.Add(0)
+	items := buildItems(t, m, []byte(corpus), 0)
+
+	prompt := func() Mix {
+		m := NewFiltered()
+		m.Add(0)
+		for _, s := range []byte("shall I compare ") {
+			m.Add(s)
+		}
+		return m
+	}
+	sampler := NewSampler(0, 0, 1, 16, 1, 1)
+
+	copier := NewCopier(items, 256, -1, -1)
+	text := copier.Generate(sampler, prompt(), copier.Search, NewText(Stop{}, nil))
+	if copier.Searches != 1 || copier.Copies != 15 {
+		t.Fatalf("%d searches %d copies", copier.Searches, copier.Copies)
+	}
+	if !strings.Contains(corpus, text.String()) {
+		t.Fatalf("%q is not copied", text.String())
+	}
+
+	copier = NewCopier(items, 256, -1, 2)
+	copier.Generate(sampler, prompt(), copier.Search, NewText(Stop{}, nil))
+	if copier.Searches != 16 || copier.Copies != 0 {
+		t.Fatalf("%d searches %d copies without verification", copier.Searches, copier.Copies)
+	}
+
+	forced := func(m Mix) ([]float32, []Neighbor) {
+		_, neighbors := copier.Search(m)
+		distribution := make([]float32, 256)
+		distribution['x'] = 1
+		return distribution, neighbors
+	}
+	copier = NewCopier(items, 256, -1, 2)
+	if text := copier.Generate(sampler, prompt(), forced, NewText(Stop{}, nil)); text.String() != strings.Repeat("x", 16) {
+		t.Fatalf("%q is not sampled from the distribution", text.String())
+	}
+
+	dfa, err := NewDFA("[^t]*", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copier, text = NewCopier(items, 256, -1, -1), NewText(Stop{}, nil)
+	text.Constraint = NewConstraint(dfa)
+	copier.Generate(sampler, prompt(), copier.Search, text)
+	if strings.Contains(text.String(), "t") || copier.Searches < 2 {
+		t.Fatalf("%q copied past the constraint with %d searches", text.String(), copier.Searches)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/pointlander/gradient/tf64"
+	"github.com/pointlander/textus/vector"
+)
+
+const (
+	// HeadSize is the size of the query, key and value projections
+	HeadSize = 32
+)
+
+// Head is a learned attention head with query, key, value and output projections
+type Head struct {
+	Symbols   int
+	Iteration int
+	Set       tf64.Set
+	Q, K, V   Matrix
+	O         Matrix
+}
+
+// NewHead makes a new attention head trained to predict symbols symbols
+func NewHead(rng *rand.Rand, symbols int) *Head {
+	set := tf64.NewSet()
+	set.Add("q", InputSize, HeadSize)
+	set.Add("k", InputSize, HeadSize)
+	set.Add("v", InputSize, HeadSize)
+	set.Add("o", HeadSize, InputSize)
+	set.Add("r", InputSize, symbols)
+	set.Add("b", symbols, 1)
+	Initialize(rng, &set)
+	h := &Head{
+		Symbols: symbols,
+		Set:     set,
+	}
+	h.Sync()
+	return h
+}
+
+// LoadHead loads an attention head from a file
+func LoadHead(name string) (*Head, error) {
+	set := tf64.NewSet()
+	_, epoch, err := set.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	h := &Head{
+		Symbols:   set.ByName["b"].S[0],
+		Iteration: epoch,
+		Set:       set,
+	}
+	h.Sync()
+	return h, nil
+}
+
+// Save saves the attention head to a file
+func (h *Head) Save(name string, cost float64) error {
+	return h.Set.Save(name, cost, h.Iteration)
+}
+
+// Sync copies the trained weights into the float32 projections
+func (h *Head) Sync() {
+	convert := func(name string) Matrix {
+		w := h.Set.ByName[name]
+		m := NewMatrix(w.S[0], w.S[1])
+		for _, v := range w.X {
+			m.Data = append(m.Data, float32(v))
+		}
+		return m
+	}
+	h.Q, h.K, h.V, h.O = convert("q"), convert("k"), convert("v"), convert("o")
+}
+
+// Attend computes the attention of the head over the rows of the input
+func (h *Head) Attend(input Matrix) []float32 {
+	q, k, v := h.Q.MulT(input), h.K.MulT(input), h.V.MulT(input)
+	scale := float32(1 / math.Sqrt(HeadSize))
+	pooled, values := make([]float32, HeadSize), make([]float32, input.Rows)
+	for i := 0; i < q.Rows; i++ {
+		query := q.Data[i*q.Cols : (i+1)*q.Cols]
+		for j := 0; j < k.Rows; j++ {
+			values[j] = vector.Dot(query, k.Data[j*k.Cols:(j+1)*k.Cols]) * scale
+		}
+		softmax(values)
+		for j, a := range values {
+			for l, x := range v.Data[j*v.Cols : (j+1)*v.Cols] {
+				pooled[l] += a * x
+			}
+		}
+	}
+	output := h.O.MulT(NewMatrix(HeadSize, 1, pooled...))
+	return Normalize(output.Data)
+}
+
+// Train accumulates the gradient of predicting symbol from the rows of input,
+// an adam step is taken if step is true
+func (h *Head) Train(input Matrix, symbol byte, step bool) float64 {
+	rows := input.Rows
+	others := tf64.NewSet()
+	others.Add("x", input.Cols, rows)
+	others.Add("y", h.Symbols, 1)
+	others.Add("scale", rows, 1)
+	others.Add("ones", rows, 1)
+	others.Add("one", 1, rows)
+	x, y := others.ByName["x"], others.ByName["y"]
+	for _, v := range input.Data {
+		x.X = append(x.X, float64(v))
+	}
+	for i := range h.Symbols {
+		if byte(i) == symbol {
+			y.X = append(y.X, -1)
+		} else {
+			y.X = append(y.X, 0)
+		}
+	}
+	for range rows {
+		others.ByName["scale"].X = append(others.ByName["scale"].X, 1/math.Sqrt(HeadSize))
+		others.ByName["ones"].X = append(others.ByName["ones"].X, 1)
+		others.ByName["one"].X = append(others.ByName["one"].X, 1)
+	}
+
+	q := tf64.Mul(h.Set.Get("q"), others.Get("x"))
+	k := tf64.Mul(h.Set.Get("k"), others.Get("x"))
+	v := tf64.Mul(h.Set.Get("v"), others.Get("x"))
+	// the softmax of each row is computed in the log domain so the rows are independent
+	scores := tf64.Hadamard(tf64.Mul(k, q), others.Get("scale"))
+	sums := tf64.Mul(others.Get("one"), tf64.Mul(others.Get("ones"), tf64.Exp(scores)))
+	attention := tf64.Exp(tf64.Sub(scores, tf64.Log(sums)))
+	pooled := tf64.SumRows(tf64.Mul(tf64.T(v), attention))
+	// the output is not normalized during training, only its direction is used
+	output := tf64.Mul(h.Set.Get("o"), pooled)
+	logits := tf64.Add(tf64.Mul(h.Set.Get("r"), output), h.Set.Get("b"))
+	loss := tf64.Add(tf64.Log(tf64.Sum(tf64.Exp(logits))), tf64.Sum(tf64.Hadamard(logits, others.Get("y"))))
+
+	cost := tf64.Gradient(loss).X[0]
+	if step {
+		h.Iteration++
+		Adam(&h.Set, h.Iteration)
+		h.Set.Zero()
+		h.Sync()
+	}
+	return cost
+}
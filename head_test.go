@@ -0,0 +1,62 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestHead(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	h := NewHead(rng, 4)
+	text := []byte{0, 1, 2, 3, 0, 1, 2, 3, 0, 1, 2, 3, 0, 1, 2, 3}
+	epoch := func(train bool) float64 {
+		m := NewFiltered()
+		total := 0.0
+		for i, s := range text {
+			if train {
+				total += h.Train(m.Rows(), s, i%4 == 3)
+			} else {
+				total += h.Train(m.Rows(), s, false)
+				h.Set.Zero()
+			}
+			m.Add(s)
+		}
+		return total
+	}
+	before := epoch(false)
+	for range 64 {
+		epoch(true)
+	}
+	after := epoch(false)
+	if after >= before {
+		t.Fatalf("%f >= %f", after, before)
+	}
+
+	name := filepath.Join(t.TempDir(), "head.bin")
+	err := h.Save(name, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadHead(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewFiltered()
+	m.Add(1)
+	m.Head = h
+	x := m.Mix()
+	m.Head = loaded
+	y := m.Mix()
+	if len(x) != InputSize {
+		t.Fatalf("%d != %d", len(x), InputSize)
+	}
+	near(t, "head", 0, x, y)
+	if norm := Normalize(append([]float32{}, x...)); CS(norm, x) < .999 {
+		t.Fatal("output is not normalized")
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"slices"
+	"sort"
+)
+
+const (
+	// InfillFile is the forward vector database of the infilling model
+	InfillFile = "infill.bin"
+	// InfillReverseFile is the backward vector database of the infilling model, the mixer is run over the reversed text
+	// so the offsets are in the reversed text
+	InfillReverseFile = "infill.reverse.bin"
+	// InfillWindow is the number of symbols of the prefix and the suffix that are scored
+	InfillWindow = 8
+)
+
+// Reversed returns the symbols in reverse order
+func Reversed(symbols []byte) []byte {
+	reversed := slices.Clone(symbols)
+	slices.Reverse(reversed)
+	return reversed
+}
+
+// Index writes the vector database of a mixer run over the symbols of a document
+func Index(output io.Writer, m Mix, symbols []byte, document uint32) error {
+	for i, s := range symbols {
+		err := WriteItem(output, m.Mix(), s, Source{Document: document, Offset: uint32(i)})
+		if err != nil {
+			return err
+		}
+		m.Add(s)
+	}
+	return nil
+}
+
+// LogProb is the log probability of symbols following the state of a mixer under the knn distribution
+// of a vector database, the mixer is advanced over the symbols
+func LogProb(items []Item, m Mix, symbols []byte, length int) float64 {
+	sum := 0.0
+	next := func(m Mix) []float32 {
+		return KNN(Search(items, m.Mix(), KNNNeighbors), length)
+	}
+	for _, logprob := range LogProbs(m, next, symbols) {
+		sum += logprob
+	}
+	return sum
+}
+
+// Middle is a candidate middle between a prefix and a suffix
+type Middle struct {
+	Symbols []byte
+	// Forward is the average log probability of the middle and the start of the suffix under the forward database
+	Forward float64
+	// Backward is the average log probability of the reversed middle and the end of the prefix under the backward database
+	Backward float64
+}
+
+// Score is the agreement of the forward and backward databases
+func (m Middle) Score() float64 {
+	return m.Forward + m.Backward
+}
+
+// Infill fills the middle between a prefix and a suffix with a forward and a backward vector database
+type Infill struct {
+	Forward  []Item
+	Backward []Item
+	// Prefix is the forward mixer after the prefix
+	Prefix Mix
+	// Suffix is the backward mixer after the reversed suffix
+	Suffix        Mix
+	PrefixSymbols []byte
+	SuffixSymbols []byte
+	Reverse       map[byte]rune
+	Length        int
+}
+
+// NewInfill makes a new infilling of the middle between prefix and suffix, forward and backward are new mixers
+func NewInfill(forward, backward []Item, prefix, suffix []byte, f, b Mix, reverse map[byte]rune, length int) *Infill {
+	for _, s := range prefix {
+		f.Add(s)
+	}
+	for _, s := range Reversed(suffix) {
+		b.Add(s)
+	}
+	return &Infill{
+		Forward:       forward,
+		Backward:      backward,
+		Prefix:        f,
+		Suffix:        b,
+		PrefixSymbols: prefix,
+		SuffixSymbols: suffix,
+		Reverse:       reverse,
+		Length:        length,
+	}
+}
+
+// Score scores a middle with both databases
+func (f *Infill) Score(symbols []byte) Middle {
+	suffix := f.SuffixSymbols[:min(InfillWindow, len(f.SuffixSymbols))]
+	prefix := Reversed(f.PrefixSymbols)[:min(InfillWindow, len(f.PrefixSymbols))]
+	middle := Middle{Symbols: symbols}
+	if n := len(symbols) + len(suffix); n > 0 {
+		middle.Forward = LogProb(f.Forward, f.Prefix.Copy(), append(slices.Clone(symbols), suffix...), f.Length) / float64(n)
+	}
+	if n := len(symbols) + len(prefix); n > 0 {
+		middle.Backward = LogProb(f.Backward, f.Suffix.Copy(), append(Reversed(symbols), prefix...), f.Length) / float64(n)
+	}
+	return middle
+}
+
+// next is the knn distribution of a vector database
+func (f *Infill) next(items []Item) func(m Mix) []float32 {
+	return func(m Mix) []float32 {
+		return KNN(Search(items, m.Mix(), KNNNeighbors), f.Length)
+	}
+}
+
+// valid is true if the symbols of a middle can be generated into text and match its constraint
+func valid(text *Text, symbols []byte) bool {
+	t := text.Copy()
+	for i, s := range symbols {
+		if t.Add(s) {
+			return i == len(symbols)-1 && t.Finish == FinishMatch
+		}
+	}
+	return t.Constraint == nil || t.Constraint.DFA.Accept[t.Constraint.State]
+}
+
+// Fill samples middles from the forward database into copies of text and from the backward database into copies
+// of the reversed text, every prefix of a forward sample and every suffix of a backward sample that can be generated
+// into text is a middle, the middles are returned from the best score to the worst
+func (f *Infill) Fill(s *Sampler, text *Text) []Middle {
+	reversed, seen := text.Reversed(), make(map[string]bool)
+	middles := make([]Middle, 0, 2*s.Samples)
+	add := func(symbols []byte) {
+		if seen[string(symbols)] || !valid(text, symbols) {
+			return
+		}
+		seen[string(symbols)] = true
+		middles = append(middles, f.Score(symbols))
+	}
+	for range s.Samples {
+		forward := s.Generate(f.Prefix.Copy(), f.next(f.Forward), text.Copy()).Symbols
+		for i := range len(forward) + 1 {
+			add(forward[:i])
+		}
+		backward := Reversed(s.Generate(f.Suffix.Copy(), f.next(f.Backward), reversed.Copy()).Symbols)
+		for i := range len(backward) + 1 {
+			add(backward[len(backward)-i:])
+		}
+	}
+	sort.SliceStable(middles, func(i, j int) bool {
+		return middles[i].Score() > middles[j].Score()
+	})
+	return middles
+}
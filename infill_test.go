@@ -0,0 +1,63 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInfill(t *testing.T) {
+	corpus := []byte(strings.Repeat("the cat sat on the mat. the dog ran to the log. ", 8))
+	load := func(symbols []byte) []Item {
+		m := NewFiltered()
+		m.Add(0)
+		return buildItems(t, m, symbols, 0)
+	}
+	forward, backward := load(corpus), load(Reversed(corpus))
+	if len(forward) != len(corpus) || len(backward) != len(corpus) {
+		t.Fatalf("%d %d != %d", len(forward), len(backward), len(corpus))
+	}
+
+	f, b := NewFiltered(), NewFiltered()
+	f.Add(0)
+	b.Add(0)
+	infill := NewInfill(forward, backward, []byte("the dog ran "), []byte(" the log."), f, b, nil, 256)
+	right, wrong := infill.Score([]byte("to")), infill.Score([]byte("on"))
+	if right.Forward <= wrong.Forward || right.Backward <= wrong.Backward {
+		t.Fatalf("%v should score better than %v", right, wrong)
+	}
+
+	middles := infill.Fill(NewSampler(1, 0, 1, 2, 2, 1), NewText(Stop{}, nil))
+	seen, lengths := make(map[string]bool), make(map[int]bool)
+	for i, middle := range middles {
+		if seen[string(middle.Symbols)] {
+			t.Fatalf("%q is a duplicate", middle.Symbols)
+		}
+		seen[string(middle.Symbols)], lengths[len(middle.Symbols)] = true, true
+		if i > 0 && middle.Score() > middles[i-1].Score() {
+			t.Fatal("middles are not sorted")
+		}
+	}
+	if !lengths[0] || !lengths[1] || !lengths[2] {
+		t.Fatalf("%v are the lengths of the middles", lengths)
+	}
+
+	dfa, err := NewDFA("t[a-z]", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := NewText(Stop{}, nil)
+	text.Constraint = NewConstraint(dfa)
+	middles = infill.Fill(NewSampler(1, 0, 1, 2, 4, 1), text)
+	if len(middles) == 0 {
+		t.Fatal("there are no middles")
+	}
+	for _, middle := range middles {
+		if s := string(middle.Symbols); len(s) != 2 || s[0] != 't' {
+			t.Fatalf("%q does not match the constraint", s)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+)
+
+const (
+	// KNNNeighbors is the number of nearest neighbors
+	KNNNeighbors = 32
+	// KNNTemperature is the temperature of the neighbor similarities
+	KNNTemperature = .01
+	// KNNDistance is the distance scale of the adaptive interpolation weight
+	KNNDistance = .01
+	// RecordSize is the size of a vector database record, an item followed by its source
+	RecordSize = ItemSize + 8
+	// ItemsMagic identifies a vector database
+	ItemsMagic = "TXVD"
+	// ItemsVersion is the version of the vector database format
+	ItemsVersion = 1
+	// HeaderSize is the size of the header of a vector database, the magic followed by the version and the record size
+	HeaderSize = 12
+)
+
+// Source is the position of a record in the corpus
+type Source struct {
+	// Document is the index of the document in Documents
+	Document uint32
+	// Offset is the offset of the symbol in the runes of the document
+	Offset uint32
+}
+
+// Item is a vector database record
+type Item struct {
+	Vector [InputSize]float32
+	Symbol byte
+	Source Source
+}
+
+// WriteHeader writes the header of a vector database, the records follow it
+func WriteHeader(output io.Writer) error {
+	buffer := [HeaderSize]byte{}
+	copy(buffer[:], ItemsMagic)
+	binary.LittleEndian.PutUint32(buffer[4:], ItemsVersion)
+	binary.LittleEndian.PutUint32(buffer[8:], RecordSize)
+	_, err := output.Write(buffer[:])
+	return err
+}
+
+// BuildItems writes the vector database name of a mixer run over the symbols of a document
+func BuildItems(name string, m Mix, symbols []byte, document uint32) error {
+	output, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	writer := bufio.NewWriter(output)
+	err = WriteHeader(writer)
+	if err != nil {
+		return err
+	}
+	err = Index(writer, m, symbols, document)
+	if err != nil {
+		return err
+	}
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+	return output.Close()
+}
+
+// LoadItems loads the vector database
+func LoadItems(name string) []Item {
+	input, err := os.Open(name)
+	if err != nil {
+		panic(err)
+	}
+	defer input.Close()
+
+	info, err := input.Stat()
+	if err != nil {
+		panic(err)
+	}
+	reader := bufio.NewReader(input)
+	header := [HeaderSize]byte{}
+	_, err = io.ReadFull(reader, header[:])
+	if err != nil || string(header[:4]) != ItemsMagic {
+		panic(fmt.Errorf("%s is not a vector database", name))
+	}
+	if version := binary.LittleEndian.Uint32(header[4:]); version != ItemsVersion {
+		panic(fmt.Errorf("%s is version %d of the vector database format, not %d", name, version, ItemsVersion))
+	}
+	if size := binary.LittleEndian.Uint32(header[8:]); size != RecordSize {
+		panic(fmt.Errorf("%s has records of %d bytes, not %d", name, size, RecordSize))
+	}
+	if (info.Size()-HeaderSize)%RecordSize != 0 {
+		panic(fmt.Errorf("%s is truncated", name))
+	}
+	length := (info.Size() - HeaderSize) / RecordSize
+
+	items := make([]Item, length)
+	buffer, vec := [RecordSize]byte{}, [InputSize]float32{}
+	for x := range length {
+		_, err := io.ReadFull(reader, buffer[:])
+		if err != nil {
+			panic(err)
+		}
+		for j := range vec {
+			value := uint32(0)
+			for k := 0; k < 4; k++ {
+				value <<= 8
+				value |= uint32(buffer[j*4+3-k])
+			}
+			vec[j] = math.Float32frombits(value)
+		}
+		items[x].Vector = vec
+		items[x].Symbol = buffer[ItemSize-1]
+		items[x].Source.Document = binary.LittleEndian.Uint32(buffer[ItemSize:])
+		items[x].Source.Offset = binary.LittleEndian.Uint32(buffer[ItemSize+4:])
+	}
+	return items
+}
+
+// WriteItem writes a vector database record
+func WriteItem(output io.Writer, vector []float32, symbol byte, source Source) error {
+	buffer := [RecordSize]byte{}
+	for i, v := range vector {
+		bits := math.Float32bits(v)
+		for j := range 4 {
+			buffer[i*4+j] = byte((bits >> (8 * j)) & 0xFF)
+		}
+	}
+	buffer[ItemSize-1] = symbol
+	binary.LittleEndian.PutUint32(buffer[ItemSize:], source.Document)
+	binary.LittleEndian.PutUint32(buffer[ItemSize+4:], source.Offset)
+	_, err := output.Write(buffer[:])
+	return err
+}
+
+// Neighbor is a nearest neighbor in the vector database
+type Neighbor struct {
+	Similarity float64
+	Symbol     byte
+	Index      int
+}
+
+// Search finds the k nearest neighbors of current in parallel
+func Search(items []Item, current []float32, k int) []Neighbor {
+	return SearchFunc(items, k, func(x int) float64 {
+		return dot(&items[x].Vector, current)
+	})
+}
+
+// SearchFunc finds the k items with the greatest similarity in parallel, similarity is the similarity of item x
+func SearchFunc(items []Item, k int, similarity func(x int) float64) []Neighbor {
+	cpus := runtime.NumCPU()
+	count := (len(items) + cpus - 1) / cpus
+	results := make(chan []Neighbor, cpus)
+	for i := range cpus {
+		begin, end := i*count, (i+1)*count
+		if end > len(items) {
+			end = len(items)
+		}
+		if begin > end {
+			begin = end
+		}
+		go func(begin, end int) {
+			result := make([]Neighbor, 0, k+1)
+			for x := begin; x < end; x++ {
+				a := similarity(x)
+				if len(result) == k && a <= result[k-1].Similarity {
+					continue
+				}
+				j := len(result)
+				if j < k {
+					result = append(result, Neighbor{})
+				} else {
+					j--
+				}
+				for j > 0 && result[j-1].Similarity < a {
+					result[j] = result[j-1]
+					j--
+				}
+				result[j] = Neighbor{a, items[x].Symbol, x}
+			}
+			results <- result
+		}(begin, end)
+	}
+
+	combine := make([]Neighbor, 0, cpus*k)
+	for range cpus {
+		combine = append(combine, <-results...)
+	}
+	sort.Slice(combine, func(i, j int) bool {
+		return combine[i].Similarity > combine[j].Similarity
+	})
+	if len(combine) > k {
+		combine = combine[:k]
+	}
+	return combine
+}
+
+// KNN computes the distribution over symbols from the nearest neighbors
+func KNN(neighbors []Neighbor, symbols int) []float32 {
+	distribution := make([]float32, symbols)
+	if len(neighbors) == 0 {
+		return distribution
+	}
+	max := neighbors[0].Similarity
+	sum := 0.0
+	for _, neighbor := range neighbors {
+		w := math.Exp((neighbor.Similarity - max) / KNNTemperature)
+		distribution[neighbor.Symbol] += float32(w)
+		sum += w
+	}
+	for i := range distribution {
+		distribution[i] /= float32(sum)
+	}
+	return distribution
+}
+
+// Interpolate interpolates the knn distribution with the model distribution,
+// a negative lambda adapts the weight to the distance of the nearest neighbor
+func Interpolate(lambda float64, neighbors []Neighbor, knn, model []float32) []float32 {
+	if lambda < 0 {
+		lambda = 0
+		if len(neighbors) > 0 {
+			distance := 1 - neighbors[0].Similarity
+			if distance < 0 {
+				distance = 0
+			}
+			lambda = math.Exp(-distance / KNNDistance)
+		}
+	}
+	distribution := make([]float32, len(model))
+	for i := range distribution {
+		distribution[i] = float32(lambda)*knn[i] + float32(1-lambda)*model[i]
+	}
+	return distribution
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// buildItems builds a vector database of a mixer run over the symbols of a document and loads it
+func buildItems(t *testing.T, m Mix, symbols []byte, document uint32) []Item {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "db.bin")
+	err := BuildItems(name, m, symbols, document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return LoadItems(name)
+}
+
+func TestLoadItems(t *testing.T) {
+	items := buildItems(t, NewFiltered(), []byte("abcd"), 0)
+	if len(items) != 4 || items[3].Symbol != 'd' || items[3].Source.Offset != 3 {
+		t.Fatalf("%d items", len(items))
+	}
+
+	dir := t.TempDir()
+	for _, data := range [][]byte{
+		make([]byte, 2*ItemSize),
+		[]byte("TXVD\x02\x00\x00\x00"),
+		append([]byte("TXVD\x01\x00\x00\x00\x01\x04\x00\x00"), make([]byte, 2*ItemSize)...),
+		append([]byte("TXVD\x01\x00\x00\x00\x09\x04\x00\x00"), make([]byte, RecordSize+1)...),
+	} {
+		name := filepath.Join(dir, "old.bin")
+		err := os.WriteFile(name, data, 0640)
+		if err != nil {
+			t.Fatal(err)
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%q is not a vector database", data[:min(len(data), HeaderSize)])
+				}
+			}()
+			LoadItems(name)
+		}()
+	}
+}
+
+func TestSearch(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	items := make([]Item, 1000)
+	for i := range items {
+		for j := range items[i].Vector {
+			items[i].Vector[j] = rng.Float32()
+		}
+		items[i].Symbol = byte(rng.Intn(4))
+	}
+	current := make([]float32, InputSize)
+	for i := range current {
+		current[i] = rng.Float32()
+	}
+	similarities := make([]float64, len(items))
+	for i := range items {
+		similarities[i] = dot(&items[i].Vector, current)
+	}
+	sort.Float64s(similarities)
+	neighbors := Search(items, current, 10)
+	if len(neighbors) != 10 {
+		t.Fatalf("%d != 10", len(neighbors))
+	}
+	for i, neighbor := range neighbors {
+		if expected := similarities[len(similarities)-1-i]; neighbor.Similarity != expected {
+			t.Fatalf("%d: %f != %f", i, neighbor.Similarity, expected)
+		}
+	}
+
+	nearest := SearchFunc(items, 3, func(x int) float64 {
+		return -float64(x)
+	})
+	if len(nearest) != 3 || nearest[0].Index != 0 || nearest[2].Index != 2 || nearest[1].Symbol != items[1].Symbol {
+		t.Fatalf("%v", nearest)
+	}
+
+	knn := KNN(neighbors, 4)
+	model := []float32{.25, .25, .25, .25}
+	for _, lambda := range []float64{-1, 0, .5, 1} {
+		sum := float32(0.0)
+		for _, v := range Interpolate(lambda, neighbors, knn, model) {
+			sum += v
+		}
+		if sum < .99 || sum > 1.01 {
+			t.Fatalf("%f: %f is not 1", lambda, sum)
+		}
+	}
+}
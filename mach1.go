@@ -11,12 +11,18 @@ import (
 	"math"
 	"math/bits"
 	"os"
+	"sort"
+	"sync"
 )
 
 // Mach1 model
 func Mach1() {
-	if *FlagPrompt != "" {
-		m := NewFiltered()
+	if *FlagPrompt != "" || *FlagREPL || *FlagServe != "" || *FlagScore {
+		meta, err := LoadMeta("db.bin")
+		if err != nil {
+			panic(err)
+		}
+		m := NewFilteredWithConfig(meta.Mixer)
 		for _, v := range []byte(*FlagPrompt) {
 			m.Add(v)
 		}
@@ -43,7 +49,12 @@ func Mach1() {
 			}
 		}
 		buffer, vectorBuffer, vector := [ItemSize]byte{}, [VectorSize]byte{}, [InputSize]float32{}
-		for i := 0; i < 128; i++ {
+		// the files and the buffers are shared so one distribution is computed at a time
+		var lock sync.Mutex
+		// next is the distribution of the records under the nearest summary vectors
+		next := func(m Mix) []float32 {
+			lock.Lock()
+			defer lock.Unlock()
 			current := m.Mix()
 			max, index := float32(0.0), 0
 			for j := range items[4] {
@@ -95,7 +106,7 @@ func Mach1() {
 					}
 				}
 			}
-			max, symbol := float32(0.0), byte(0)
+			neighbors := make([]Neighbor, 0, 8)
 			input[0].Seek(int64(index*8*len(buffer)), 0)
 			for k := index * 8; k < (index+1)*8; k++ {
 				n, err := input[0].Read(buffer[:])
@@ -115,14 +126,35 @@ func Mach1() {
 					}
 					vector[j] = math.Float32frombits(value)
 				}
-				if a := CS(vector[:], current[:]); a > max {
-					max, symbol = a, buffer[ItemSize-1]
-				}
+				neighbors = append(neighbors, Neighbor{float64(CS(vector[:], current[:])), buffer[ItemSize-1], k})
 			}
-			fmt.Printf("%c", symbol)
-			m.Add(symbol)
+			sort.Slice(neighbors, func(i, j int) bool {
+				return neighbors[i].Similarity > neighbors[j].Similarity
+			})
+			return KNN(neighbors, 256)
+		}
+		sampler := FlagSampler(128, 1)
+		// the symbols are bytes
+		forward, reverse := make(map[rune]byte), make(map[byte]rune)
+		for i := range 256 {
+			forward[rune(i)], reverse[byte(i)] = byte(i), rune(i)
+		}
+		if *FlagREPL {
+			NewREPL(m, next, forward, reverse, sampler, FlagText(reverse)).Run(os.Stdin, os.Stdout)
+			return
+		}
+		model := Model{Mix: m, Distribution: next, Forward: forward, Reverse: reverse}
+		if *FlagScore {
+			ScoreCandidates(model)
+			return
+		}
+		if *FlagServe != "" {
+			Serve(*FlagServe, NewServer(model, sampler, FlagText(reverse), *FlagConcurrency))
+			return
+		}
+		for range sampler.Samples {
+			sampler.Generate(m.Copy(), next, FlagText(nil)).Print()
 		}
-		fmt.Println()
 		return
 	}
 
@@ -152,7 +184,12 @@ func Mach1() {
 		defer db[i].Close()
 	}
 
-	m := NewFiltered()
+	config := FlagMixerConfig()
+	err = SaveMeta("db.bin", Meta{Mixer: config})
+	if err != nil {
+		panic(err)
+	}
+	m := NewFilteredWithConfig(config)
 	m.Add(0)
 	buffer32, buffer8 := make([]byte, 4), make([]byte, 1)
 	for _, v := range data {
@@ -6,11 +6,9 @@ package main
 
 import (
 	"compress/bzip2"
-	"fmt"
 	"io"
-	"math"
-	"math/rand"
 	"os"
+	"sync"
 )
 
 // Mach2 mach 2 model
@@ -38,103 +36,38 @@ func Mach2() {
 		}
 	}
 
-	if *FlagPrompt != "" {
-		m := NewFiltered()
-		for _, v := range []rune(*FlagPrompt) {
-			m.Add(forward[v])
-		}
-
-		input, err := os.Open("db.bin")
+	if *FlagPrompt != "" || *FlagREPL || *FlagServe != "" || *FlagScore {
+		meta, err := LoadMeta("db.bin")
 		if err != nil {
 			panic(err)
 		}
-		defer input.Close()
-
-		info, err := input.Stat()
-		if err != nil {
-			panic(err)
+		m := NewFilteredWithMeta(meta)
+		for _, v := range []rune(*FlagPrompt) {
+			m.Add(forward[v])
 		}
-		length := info.Size() / ItemSize
+		items := LoadItems("db.bin")
 
-		rng := rand.New(rand.NewSource(1))
-		current := m.Mix()
-		var search func(samples, begin, end int) byte
-		search = func(samples, begin, end int) byte {
-			buffer, vector := [ItemSize]byte{}, [InputSize]float32{}
+		sampler := FlagSampler(256, 1)
+		rng := sampler.Rng
+		var search func(current []float32, samples, begin, end int) []float32
+		search = func(current []float32, samples, begin, end int) []float32 {
 			if end-begin <= Samples {
-				input.Seek(int64(begin*len(buffer)), 0)
-				max, symbol := float32(0.0), byte(0)
-				for range end - begin {
-					n, err := input.Read(buffer[:])
-					if err == io.EOF {
-						panic("symbol not found")
-					} else if err != nil {
-						panic(err)
-					}
-					if n != len(buffer) {
-						panic("not all bytes read")
-					}
-					for j := range vector {
-						value := uint32(0)
-						for k := 0; k < 4; k++ {
-							value <<= 8
-							value |= uint32(buffer[j*4+3-k])
-						}
-						vector[j] = math.Float32frombits(value)
-					}
-					if a := CS(vector[:], current[:]); a > max {
-						max, symbol = a, buffer[len(buffer)-1]
-					}
-				}
-				return symbol
+				neighbors := SearchFunc(items[begin:end], KNNNeighbors, func(x int) float64 {
+					return float64(CS(items[begin+x].Vector[:], current))
+				})
+				return KNN(neighbors, len(forward))
 			}
 			a, b := float32(0.0), float32(0.0)
 			aa, bb := make([]float32, 0, Samples), make([]float32, 0, Samples)
 			for range Samples {
 				index := rng.Intn(end-begin)/2 + begin
-				input.Seek(int64(index*len(buffer)), 0)
-				n, err := input.Read(buffer[:])
-				if err == io.EOF {
-					continue
-				} else if err != nil {
-					panic(err)
-				}
-				if n != len(buffer) {
-					panic("not all bytes read")
-				}
-				for j := range vector {
-					value := uint32(0)
-					for k := 0; k < 4; k++ {
-						value <<= 8
-						value |= uint32(buffer[j*4+3-k])
-					}
-					vector[j] = math.Float32frombits(value)
-				}
-				cs := CS(vector[:], current[:])
+				cs := CS(items[index].Vector[:], current)
 				a += cs
 				aa = append(aa, cs)
 			}
 			for range Samples {
-				index := end - rng.Intn(end-begin)/2
-				input.Seek(int64(index*len(buffer)), 0)
-				n, err := input.Read(buffer[:])
-				if err == io.EOF {
-					continue
-				} else if err != nil {
-					panic(err)
-				}
-				if n != len(buffer) {
-					panic("not all bytes read")
-				}
-				for j := range vector {
-					value := uint32(0)
-					for k := 0; k < 4; k++ {
-						value <<= 8
-						value |= uint32(buffer[j*4+3-k])
-					}
-					vector[j] = math.Float32frombits(value)
-				}
-				cs := CS(vector[:], current[:])
+				index := end - 1 - rng.Intn(end-begin)/2
+				cs := CS(items[index].Vector[:], current)
 				b += cs
 				bb = append(bb, cs)
 			}
@@ -155,51 +88,49 @@ func Mach2() {
 				samples >>= 1
 			}
 			if va < vb {
-				return search(samples, begin, begin+(end-begin)/2)
+				return search(current, samples, begin, begin+(end-begin)/2)
 			}
-			return search(samples, begin+(end-begin)/2, end)
+			return search(current, samples, begin+(end-begin)/2, end)
+		}
+		// the random numbers are shared so one distribution is computed at a time
+		var lock sync.Mutex
+		next := func(m Mix) []float32 {
+			lock.Lock()
+			defer lock.Unlock()
+			return search(m.Mix(), Samples, 0, len(items))
+		}
+		if *FlagREPL {
+			NewREPL(m, next, forward, reverse, sampler, FlagText(reverse)).Run(os.Stdin, os.Stdout)
+			return
+		}
+		model := Model{Mix: m, Distribution: next, Forward: forward, Reverse: reverse}
+		if *FlagScore {
+			ScoreCandidates(model)
+			return
+		}
+		if *FlagServe != "" {
+			Serve(*FlagServe, NewServer(model, sampler, FlagText(reverse), *FlagConcurrency))
+			return
 		}
-		for range 256 {
-			symbol := search(Samples, 0, int(length))
-			fmt.Printf("%c", reverse[symbol])
-			m.Add(symbol)
-			current = m.Mix()
+		for range sampler.Samples {
+			sampler.Generate(m.Copy(), next, FlagText(reverse)).Print()
 		}
 		return
 	}
 
-	db, err := os.Create("db.bin")
+	meta := Meta{Mixer: FlagMixerConfig()}
+	err = SaveMeta("db.bin", meta)
 	if err != nil {
 		panic(err)
 	}
-	defer db.Close()
-
-	m := NewFiltered()
+	m := NewFilteredWithMeta(meta)
 	m.Add(0)
-	buffer32, buffer8 := make([]byte, 4), make([]byte, 1)
+	symbols := make([]byte, 0, len(data))
 	for _, v := range string(data) {
-		vector := m.Mix()
-		for _, v := range vector {
-			bits := math.Float32bits(v)
-			for i := range buffer32 {
-				buffer32[i] = byte((bits >> (8 * i)) & 0xFF)
-			}
-			n, err := db.Write(buffer32)
-			if err != nil {
-				panic(err)
-			}
-			if n != len(buffer32) {
-				panic("4 bytes should be been written")
-			}
-		}
-		buffer8[0] = forward[v]
-		n, err := db.Write(buffer8)
-		if err != nil {
-			panic(err)
-		}
-		if n != 1 {
-			panic("1 byte should be been written")
-		}
-		m.Add(forward[v])
+		symbols = append(symbols, forward[v])
+	}
+	err = BuildItems("db.bin", m, symbols, 0)
+	if err != nil {
+		panic(err)
 	}
 }
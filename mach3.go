@@ -6,11 +6,11 @@ package main
 
 import (
 	"compress/bzip2"
-	"fmt"
 	"io"
 	"math/bits"
 	"math/rand"
 	"os"
+	"runtime"
 
 	"github.com/pointlander/textus/vector"
 )
@@ -55,103 +55,89 @@ func Mach3() {
 		}
 	}
 
-	if *FlagPrompt != "" {
-		m := NewFiltered()
-		for _, v := range []rune(*FlagPrompt) {
-			m.Add(forward[v])
+	// hash is the locality sensitive hash of a mixer vector
+	hash := func(vec []float32) [2]uint64 {
+		bit := [2]uint64{}
+		for j := range vectors {
+			bit[j/64] <<= 1
+			a, b := vector.Dot(vectors[j][0][:], vec), vector.Dot(vectors[j][1][:], vec)
+			if a > b {
+				bit[j/64] |= 1
+			}
 		}
+		return bit
+	}
 
-		input, err := os.Open("db.bin")
+	if *FlagPrompt != "" || *FlagREPL || *FlagServe != "" || *FlagScore {
+		meta, err := LoadMeta("db.bin")
 		if err != nil {
 			panic(err)
 		}
-		defer input.Close()
-
-		info, err := input.Stat()
-		if err != nil {
-			panic(err)
+		m := NewFilteredWithMeta(meta)
+		for _, v := range []rune(*FlagPrompt) {
+			m.Add(forward[v])
 		}
-		length := info.Size() / (2 * 8)
 
-		buffer64 := make([]byte, 8)
-		items := make([][2]uint64, length)
-		for i := range items {
-			for j := range items[i] {
-				n, err := input.Read(buffer64)
-				if err == io.EOF {
-					panic("symbol not found")
-				} else if err != nil {
-					panic(err)
+		items := LoadItems("db.bin")
+		hashes := make([][2]uint64, len(items))
+		cpus := runtime.NumCPU()
+		count := (len(items) + cpus - 1) / cpus
+		done := make(chan bool, cpus)
+		for i := range cpus {
+			begin, end := min(i*count, len(items)), min((i+1)*count, len(items))
+			go func(begin, end int) {
+				for x := begin; x < end; x++ {
+					hashes[x] = hash(items[x].Vector[:])
 				}
-				if n != len(buffer64) {
-					panic("not all bytes read")
-				}
-				value := uint64(0)
-				for k := 0; k < 8; k++ {
-					value <<= 8
-					value |= uint64(buffer64[7-k])
-				}
-				items[i][j] = value
-			}
+				done <- true
+			}(begin, end)
+		}
+		for range cpus {
+			<-done
 		}
 
-		datum := []rune(string(data))
-		for i := 0; i < 256; i++ {
-			vec := m.Mix()
-			bit := [2]uint64{}
-			for j := range vectors {
-				bit[j/64] <<= 1
-				a, b := vector.Dot(vectors[j][0][:], vec[:]), vector.Dot(vectors[j][1][:], vec[:])
-				if a > b {
-					bit[j/64] |= 1
-				}
-			}
-
-			min, index := 64, 0
-			for j := range items {
-				if a := bits.OnesCount64(items[j][0]^bit[0]) + bits.OnesCount64(items[j][1]^bit[1]); a < min {
-					min, index = a, j
-				}
-			}
-			symbol := datum[index]
-			fmt.Printf("%c", symbol)
-			m.Add(forward[symbol])
+		// next is the distribution of the neighbors with the nearest hashes, the similarity is the fraction of equal bits
+		next := func(m Mix) []float32 {
+			bit := hash(m.Mix())
+			neighbors := SearchFunc(items, KNNNeighbors, func(x int) float64 {
+				distance := bits.OnesCount64(hashes[x][0]^bit[0]) + bits.OnesCount64(hashes[x][1]^bit[1])
+				return 1 - float64(distance)/128
+			})
+			return KNN(neighbors, len(forward))
+		}
+		sampler := FlagSampler(256, 1)
+		if *FlagREPL {
+			NewREPL(m, next, forward, reverse, sampler, FlagText(reverse)).Run(os.Stdin, os.Stdout)
+			return
+		}
+		model := Model{Mix: m, Distribution: next, Forward: forward, Reverse: reverse}
+		if *FlagScore {
+			ScoreCandidates(model)
+			return
+		}
+		if *FlagServe != "" {
+			Serve(*FlagServe, NewServer(model, sampler, FlagText(reverse), *FlagConcurrency))
+			return
+		}
+		for range sampler.Samples {
+			sampler.Generate(m.Copy(), next, FlagText(reverse)).Print()
 		}
 		return
 	}
 
-	db, err := os.Create("db.bin")
+	meta := Meta{Mixer: FlagMixerConfig()}
+	err = SaveMeta("db.bin", meta)
 	if err != nil {
 		panic(err)
 	}
-	defer db.Close()
-
-	m := NewFiltered()
+	m := NewFilteredWithMeta(meta)
 	m.Add(0)
-	buffer64 := make([]byte, 8)
+	symbols := make([]byte, 0, len(data))
 	for _, v := range string(data) {
-		vec := m.Mix()
-		bits := [2]uint64{}
-		for j := range bits {
-			for i := range vectors {
-				bits[j] <<= 1
-				a, b := vector.Dot(vectors[i][0][:], vec[:]), vector.Dot(vectors[i][1][:], vec[:])
-				if a > b {
-					bits[j] |= 1
-				}
-			}
-
-			for i := range buffer64 {
-				buffer64[i] = byte((bits[j] >> (8 * i)) & 0xFF)
-			}
-			n, err := db.Write(buffer64)
-			if err != nil {
-				panic(err)
-			}
-			if n != len(buffer64) {
-				panic("8 bytes should be been written")
-			}
-		}
-		m.Add(forward[v])
+		symbols = append(symbols, forward[v])
+	}
+	err = BuildItems("db.bin", m, symbols, 0)
+	if err != nil {
+		panic(err)
 	}
 }
@@ -8,8 +8,6 @@ import (
 	"compress/bzip2"
 	"fmt"
 	"io"
-	"math"
-	"math/rand"
 	"os"
 	"runtime"
 	"sort"
@@ -42,51 +40,19 @@ func Mach4() {
 		}
 	}
 
-	if *FlagPrompt != "" {
-		m := NewFiltered()
-		for _, v := range []rune(*FlagPrompt) {
-			m.Add(forward[v])
-		}
-
-		input, err := os.Open("db.bin")
+	if *FlagPrompt != "" || *FlagREPL || *FlagServe != "" || *FlagScore {
+		meta, err := LoadMeta("db.bin")
 		if err != nil {
 			panic(err)
 		}
-		defer input.Close()
-
-		info, err := input.Stat()
-		if err != nil {
-			panic(err)
+		m, prompt := NewFilteredWithMeta(meta), []byte{}
+		for _, v := range []rune(*FlagPrompt) {
+			m.Add(forward[v])
+			prompt = append(prompt, forward[v])
 		}
-		length := info.Size() / ItemSize
 
-		type Item struct {
-			Vector [InputSize]float32
-			Symbol byte
-		}
-		items := make([]Item, length)
-		buffer, vec := [ItemSize]byte{}, [InputSize]float32{}
-		for x := range length {
-			n, err := input.Read(buffer[:])
-			if err == io.EOF {
-				panic("symbol not found")
-			} else if err != nil {
-				panic(err)
-			}
-			if n != len(buffer) {
-				panic("not all bytes read")
-			}
-			for j := range vec {
-				value := uint32(0)
-				for k := 0; k < 4; k++ {
-					value <<= 8
-					value |= uint32(buffer[j*4+3-k])
-				}
-				vec[j] = math.Float32frombits(value)
-			}
-			items[x].Vector = vec
-			items[x].Symbol = buffer[ItemSize-1]
-		}
+		items := LoadItems("db.bin")
+		length := len(items)
 
 		if *FlagCompress {
 			test, err := os.Create("test.txt")
@@ -115,12 +81,15 @@ func Mach4() {
 		type Result struct {
 			Max    float64
 			Symbol byte
+			Index  int
 			Vector [InputSize]float32
 			Rank   float64
 		}
 
-		var search func(rng *rand.Rand, current []float32) (float64, byte)
-		search = func(rng *rand.Rand, current []float32) (float64, byte) {
+		sampler := FlagSampler(256, 8)
+		// vote is the pagerank vote of the nearest neighbors of the mixer and the neighbors from the most to the least similar
+		vote := func(m Mix) ([]float32, []Neighbor) {
+			current := m.Mix()
 			results := make(chan [10]Result, 8)
 			for i := range cpus {
 				begin, end := i*count, (i+1)*count
@@ -139,14 +108,13 @@ func Mach4() {
 							j++
 						}
 						if j > 0 {
-							result[j-1] = Result{a, items[x].Symbol, items[x].Vector, 0.0}
+							result[j-1] = Result{a, items[x].Symbol, begin + x, items[x].Vector, 0.0}
 						}
 					}
 					results <- result
 				}(begin, end)
 			}
 
-			max, symbol := 0.0, byte(0)
 			combine := make([]Result, 0, 8)
 			for range cpus {
 				result := <-results
@@ -165,71 +133,121 @@ func Mach4() {
 			graph.Rank(1.0, 1e-3, func(node uint32, rank float64) {
 				combine[node].Rank = rank
 			})
-			total, selection, index := 0.0, rng.Float64(), 0
+			distribution, neighbors := make([]float32, 256), make([]Neighbor, len(combine))
 			for j := range combine {
-				total += combine[j].Rank
-				if selection < total {
-					index = j
-					break
-				}
+				distribution[combine[j].Symbol] += float32(combine[j].Rank)
+				neighbors[j] = Neighbor{combine[j].Max, combine[j].Symbol, combine[j].Index}
+			}
+			return distribution, neighbors
+		}
+		var suffix *Suffix
+		if *FlagExact {
+			symbols := make([]byte, 0, len(data))
+			for _, v := range string(data) {
+				symbols = append(symbols, forward[v])
+			}
+			suffix = NewSuffix(symbols, 256)
+		}
+		mixer := func() Mix {
+			if suffix != nil {
+				return NewSuffixed(m.Copy(), suffix, prompt)
+			}
+			return m.Copy()
+		}
+		// rank is the vote blended with the longest exact match of a suffixed mixer
+		rank := func(m Mix) ([]float32, []Neighbor) {
+			distribution, neighbors := vote(m)
+			if s, ok := m.(*Suffixed); ok {
+				distribution = s.Match().Blend(distribution)
+			}
+			return distribution, neighbors
+		}
+		next := func(m Mix) []float32 {
+			distribution, _ := rank(m)
+			return distribution
+		}
+		if *FlagREPL {
+			NewREPL(mixer(), next, forward, reverse, sampler, FlagText(reverse)).Run(os.Stdin, os.Stdout)
+			return
+		}
+		model := Model{Mix: mixer(), Distribution: next, Forward: forward, Reverse: reverse}
+		if *FlagScore {
+			ScoreCandidates(model)
+			return
+		}
+		if *FlagServe != "" {
+			Serve(*FlagServe, NewServer(model, sampler, FlagText(reverse), *FlagConcurrency))
+			return
+		}
+		if copier := FlagCopier(items, len(forward)); copier != nil {
+			for range sampler.Samples {
+				text := copier.Generate(sampler, mixer(), rank, FlagText(reverse))
+				text.Print()
+				ShowProvenance(items, m.Copy(), text)
+			}
+			copier.Print()
+			return
+		}
+		if *FlagMCTS > 0 {
+			t := MCTS{
+				Iterations:  *FlagMCTS,
+				Depth:       *FlagDepth,
+				Length:      sampler.Length,
+				Exploration: 1,
+				Expand: func(m Mix) []Expansion {
+					return PageRank(items, Search(items, m.Mix(), KNNNeighbors))
+				},
 			}
-			max, symbol = combine[index].Max, combine[index].Symbol
-			return max, symbol
-		}
-		rng := rand.New(rand.NewSource(1))
-		max, symbols := 0.0, ""
-		for i := 0; i < 8; i++ {
-			sum, s := 0.0, ""
-			cp := m.Copy()
-			for range 256 {
-				current := cp.Mix()
-				max, symbol := search(rng, current)
-				sum += max
-				s += fmt.Sprintf("%c", reverse[symbol])
-				cp.Add(symbol)
+			text := t.Search(m.Copy(), FlagText(reverse))
+			text.Print()
+			ShowProvenance(items, m, text)
+			return
+		}
+		if *FlagBeam > 0 {
+			search := FlagBeamSearch(sampler.Length)
+			beams := search.Search(mixer(), next, FlagText(reverse))
+			search.Print(beams, sampler.Samples)
+			if len(beams) > 0 {
+				ShowProvenance(items, m, beams[0].Text)
+			}
+			return
+		}
+		tally := func(m Mix) []float32 {
+			t := m.(*Tally)
+			distribution, neighbors := rank(t.Mixer)
+			t.Votes = make([]float32, 256)
+			for j := len(neighbors) - 1; j >= 0; j-- {
+				t.Votes[neighbors[j].Symbol] = float32(neighbors[j].Similarity)
 			}
-			if sum > max {
+			return distribution
+		}
+		max, symbols := 0.0, NewText(FlagStop(), reverse)
+		for range sampler.Samples {
+			s, cp := sampler.Decode(&Tally{Mixer: mixer()}, tally, FlagText(reverse))
+			if sum := cp.(*Tally).Sum; sum > max {
 				max, symbols = sum, s
 			}
 		}
-		fmt.Println(symbols)
+		symbols.Print()
+		ShowProvenance(items, m, symbols)
 		return
 	}
 
 	if *FlagBuild {
-		db, err := os.Create("db.bin")
+		meta := Meta{Mixer: FlagMixerConfig(), Head: *FlagHead}
+		err := SaveMeta("db.bin", meta)
 		if err != nil {
 			panic(err)
 		}
-		defer db.Close()
-
-		m := NewFiltered()
+		m := NewFilteredWithMeta(meta)
 		m.Add(0)
-		buffer32, buffer8 := make([]byte, 4), make([]byte, 1)
+		symbols := make([]byte, 0, len(data))
 		for _, v := range string(data) {
-			vector := m.Mix()
-			for _, v := range vector {
-				bits := math.Float32bits(v)
-				for i := range buffer32 {
-					buffer32[i] = byte((bits >> (8 * i)) & 0xFF)
-				}
-				n, err := db.Write(buffer32)
-				if err != nil {
-					panic(err)
-				}
-				if n != len(buffer32) {
-					panic("4 bytes should be been written")
-				}
-			}
-			buffer8[0] = forward[v]
-			n, err := db.Write(buffer8)
-			if err != nil {
-				panic(err)
-			}
-			if n != 1 {
-				panic("1 byte should be been written")
-			}
-			m.Add(forward[v])
+			symbols = append(symbols, forward[v])
+		}
+		err = BuildItems("db.bin", m, symbols, 0)
+		if err != nil {
+			panic(err)
 		}
 		return
 	}
@@ -13,9 +13,10 @@ import (
 	"math/rand"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/pointlander/gradient/tf64"
-	"github.com/pointlander/textus/mat64"
+	"github.com/pointlander/textus/mat"
 
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
@@ -65,6 +66,7 @@ func Mach5() {
 				cov[i][ii] = make([]float64, size)
 			}
 		}
+		config := FlagMixerConfig()
 		if errors.Is(err, os.ErrNotExist) {
 			out, err := os.Create(fileName)
 			if err != nil {
@@ -72,7 +74,7 @@ func Mach5() {
 			}
 			defer out.Close()
 
-			m := mat64.NewMixer(size)
+			m := mat.NewMixerWithConfig[float64](size, config)
 			m.Add(0)
 			symbols := []rune(string(data))
 			for _, symbol := range symbols {
@@ -93,7 +95,7 @@ func Mach5() {
 				}
 			}
 
-			m = mat64.NewMixer(size)
+			m = mat.NewMixerWithConfig[float64](size, config)
 			m.Add(0)
 			cov := make([][][]float64, length)
 			for i := range cov {
@@ -213,6 +215,10 @@ func Mach5() {
 			panic(err)
 		}
 		defer out.Close()
+		err = SaveMeta("model.bin", Meta{Mixer: MixerConfig(config)})
+		if err != nil {
+			panic(err)
+		}
 
 		{
 			buffer64 := make([]byte, 8)
@@ -473,12 +479,16 @@ func Mach5() {
 		panic(err)
 	}
 	defer input.Close()
+	meta, err := LoadMeta("model.bin")
+	if err != nil {
+		panic(err)
+	}
 
-	avg, a, ai := make([]mat64.Matrix, length), make([]mat64.Matrix, length), make([]mat64.Matrix, length)
+	avg, a, ai := make([]mat.Matrix[float64], length), make([]mat.Matrix[float64], length), make([]mat.Matrix[float64], length)
 	for i := range length {
-		avg[i] = mat64.NewMatrix(size, 1)
-		a[i] = mat64.NewMatrix(size, size)
-		ai[i] = mat64.NewMatrix(size, size)
+		avg[i] = mat.NewMatrix[float64](size, 1)
+		a[i] = mat.NewMatrix[float64](size, size)
+		ai[i] = mat.NewMatrix[float64](size, size)
 	}
 	{
 		buffer64 := make([]byte, 8)
@@ -568,20 +578,28 @@ func Mach5() {
 	}
 	fmt.Println(count, total, count/total)
 
-	rng := rand.New(rand.NewSource(1))
+	sampler := FlagSampler(33, 1)
+	rng := sampler.Rng
 
-	m := mat64.NewMixer(size)
+	m := mat.NewMixerWithConfig[float64](size, meta.Mixer)
 	m.Add(0)
-	symbols := []rune("What is the meaning of life?")
+	prompt, modes := *FlagPrompt, *FlagREPL || *FlagServe != "" || *FlagScore
+	if prompt == "" && !modes {
+		prompt = "What is the meaning of life?"
+	}
+	symbols := []rune(prompt)
 	for _, symbol := range symbols {
 		code := forward[symbol]
 		m.Add(code)
 	}
 
-	sample := ""
-	for range 33 {
-		histogram := make([]int, length)
-		vector := mat64.NewMatrix(size, 1, m.Mix()...)
+	// the random numbers are shared so one distribution is computed at a time
+	var lock sync.Mutex
+	next := func(m Mix) []float32 {
+		lock.Lock()
+		defer lock.Unlock()
+		histogram := make([]float32, length)
+		vector := mat.NewMatrix[float64](size, 1, m.(*mix64).Mixer.Mix()...)
 		for i := 0; i < 33; i++ {
 			min, index := math.MaxFloat64, 0
 			for ii := range length {
@@ -597,18 +615,57 @@ func Mach5() {
 			}
 			histogram[index]++
 		}
-		fmt.Println(histogram)
-		index, sum, target := 0, 0, rng.Intn(16)
-		for i, v := range histogram {
-			sum += v
-			if target < sum {
-				index = i
-				break
-			}
+		if !modes {
+			fmt.Println(histogram)
+		}
+		for i := range histogram {
+			histogram[i] /= 33
+		}
+		return histogram
+	}
+	if *FlagREPL {
+		NewREPL(&mix64{m}, next, forward, reverse, sampler, FlagText(reverse)).Run(os.Stdin, os.Stdout)
+		return
+	}
+	model := Model{Mix: &mix64{m}, Distribution: next, Forward: forward, Reverse: reverse}
+	if *FlagScore {
+		ScoreCandidates(model)
+		return
+	}
+	if *FlagServe != "" {
+		Serve(*FlagServe, NewServer(model, sampler, FlagText(reverse), *FlagConcurrency))
+		return
+	}
+	for range sampler.Samples {
+		sample := FlagText(reverse)
+		sample.Stream = func(_ int, r rune) {
+			fmt.Printf("'%c' %d\n", r, r)
 		}
-		fmt.Printf("'%c' %d\n", reverse[byte(index)], reverse[byte(index)])
-		sample += fmt.Sprintf("%c", reverse[byte(index)])
-		m.Add(byte(index))
+		sampler.Generate(&mix64{m.Copy()}, next, sample).Print()
+	}
+}
+
+// mix64 is a mixer with float64 outputs used as a Mix
+type mix64 struct {
+	Mixer mat.Mix[float64]
+}
+
+// Copy copies the mixer
+func (m *mix64) Copy() Mix {
+	return &mix64{m.Mixer.Copy()}
+}
+
+// Add adds a symbol to the mixer
+func (m *mix64) Add(s byte) {
+	m.Mixer.Add(s)
+}
+
+// Mix is the output of the mixer
+func (m *mix64) Mix() []float32 {
+	output := m.Mixer.Mix()
+	mix := make([]float32, len(output))
+	for i, v := range output {
+		mix[i] = float32(v)
 	}
-	fmt.Println(sample)
+	return mix
 }
@@ -0,0 +1,97 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"compress/bzip2"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Mach6 is the mach 6 model, a logistic context mixing predictor
+func Mach6() {
+	file, err := Data.Open("books/100.txt.utf-8.bz2")
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	reader := bzip2.NewReader(file)
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		panic(err)
+	}
+
+	forward, reverse, code := make(map[rune]byte), make(map[byte]rune), byte(0)
+	for _, v := range string(data) {
+		if _, ok := forward[v]; !ok {
+			forward[v] = code
+			reverse[code] = v
+			code++
+			if code > 255 {
+				panic("not enough codes")
+			}
+		}
+	}
+
+	if *FlagCompress {
+		p := NewPredictor()
+		encoder := NewEncoder()
+		symbols := 0
+		for _, v := range string(data) {
+			s := forward[v]
+			for i := 7; i >= 0; i-- {
+				bit := int(s>>i) & 1
+				encoder.Encode(bit, p.P())
+				p.Update(bit)
+			}
+			symbols++
+		}
+		output := encoder.Flush()
+		err := os.WriteFile("mach6.bin", output, 0640)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(len(data), len(output), 8*float64(len(output))/float64(symbols))
+		return
+	}
+
+	if *FlagPrompt != "" || *FlagREPL || *FlagServe != "" || *FlagScore {
+		p := NewPredictor()
+		for _, v := range string(data) {
+			p.Add(forward[v])
+		}
+		m := p.Copy()
+		for _, v := range []rune(*FlagPrompt) {
+			m.Add(forward[v])
+		}
+		next := func(m Mix) []float32 {
+			return m.Mix()
+		}
+		sampler := FlagSampler(256, 1)
+		if *FlagREPL {
+			NewREPL(m, next, forward, reverse, sampler, FlagText(reverse)).Run(os.Stdin, os.Stdout)
+			return
+		}
+		model := Model{Mix: m, Distribution: next, Forward: forward, Reverse: reverse}
+		if *FlagScore {
+			ScoreCandidates(model)
+			return
+		}
+		if *FlagServe != "" {
+			Serve(*FlagServe, NewServer(model, sampler, FlagText(reverse), *FlagConcurrency))
+			return
+		}
+		if *FlagBeam > 0 {
+			search := FlagBeamSearch(sampler.Length)
+			search.Print(search.Search(m, next, FlagText(reverse)), sampler.Samples)
+			return
+		}
+		for range sampler.Samples {
+			sampler.Generate(m.Copy(), next, FlagText(reverse)).Print()
+		}
+		return
+	}
+}
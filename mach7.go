@@ -0,0 +1,199 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"compress/bzip2"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"reflect"
+)
+
+const (
+	// ReadoutBatch is the batch size for training the readout
+	ReadoutBatch = 64
+	// ReadoutFile is the file the readout is saved to
+	ReadoutFile = "readout.bin"
+	// HeadFile is the default file the learned attention head is saved to
+	HeadFile = "head.bin"
+	// HeadExamples is the number of examples the attention head is trained on
+	HeadExamples = 64 * 1024
+	// HeadBatch is the batch size for training the attention head
+	HeadBatch = 32
+)
+
+// Mach7 is the mach 7 model, a softmax readout trained on mixer vectors
+func Mach7() {
+	file, err := Data.Open("books/100.txt.utf-8.bz2")
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	reader := bzip2.NewReader(file)
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		panic(err)
+	}
+
+	forward, reverse, code := make(map[rune]byte), make(map[byte]rune), byte(0)
+	for _, v := range string(data) {
+		if _, ok := forward[v]; !ok {
+			forward[v] = code
+			reverse[code] = v
+			code++
+			if code > 255 {
+				panic("not enough codes")
+			}
+		}
+	}
+	length := len(forward)
+	symbols := []rune(string(data))
+	split := len(symbols) - len(symbols)/10
+
+	evaluate := func(r *Readout, meta Meta) float64 {
+		m := NewFilteredWithMeta(meta)
+		m.Add(0)
+		for _, v := range symbols[:split] {
+			m.Add(forward[v])
+		}
+		total := 0.0
+		for _, v := range symbols[split:] {
+			distribution := r.Distribution(m.Mix())
+			total -= math.Log2(float64(distribution[forward[v]]) + 1e-30)
+			m.Add(forward[v])
+		}
+		return total / float64(len(symbols)-split)
+	}
+
+	if *FlagTrainHead {
+		name := *FlagHead
+		if name == "" {
+			name = HeadFile
+		}
+		rng := rand.New(rand.NewSource(1))
+		h := NewHead(rng, length)
+		m := NewFilteredWithConfig(FlagMixerConfig())
+		m.Add(0)
+		cost := 0.0
+		for i, v := range symbols[:HeadExamples] {
+			step := (i+1)%HeadBatch == 0
+			cost += h.Train(m.Rows(), forward[v], step)
+			if step {
+				if h.Iteration%64 == 0 {
+					fmt.Println(i, cost/HeadBatch)
+				}
+				cost = 0
+			}
+			m.Add(forward[v])
+		}
+		err := h.Save(name, cost)
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	if *FlagBuild {
+		meta := Meta{Mixer: FlagMixerConfig(), Head: *FlagHead}
+		err := SaveMeta(ReadoutFile, meta)
+		if err != nil {
+			panic(err)
+		}
+		rng := rand.New(rand.NewSource(1))
+		r := NewReadout(rng, length)
+		m := NewFilteredWithMeta(meta)
+		m.Add(0)
+		vectors, targets, cost := make([][]float32, 0, ReadoutBatch), make([]byte, 0, ReadoutBatch), 0.0
+		for i, v := range symbols[:split] {
+			vectors = append(vectors, m.Mix())
+			targets = append(targets, forward[v])
+			if len(vectors) == ReadoutBatch {
+				cost = r.Train(vectors, targets)
+				if r.Iteration%1024 == 0 {
+					fmt.Println(i, cost)
+				}
+				vectors, targets = vectors[:0], targets[:0]
+			}
+			m.Add(forward[v])
+		}
+		err = r.Save(ReadoutFile, cost)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println("bits per symbol", evaluate(r, meta))
+		return
+	}
+
+	r, err := LoadReadout(ReadoutFile)
+	if err != nil {
+		panic(err)
+	}
+	meta, err := LoadMeta(ReadoutFile)
+	if err != nil {
+		panic(err)
+	}
+
+	if *FlagEval {
+		fmt.Println("bits per symbol", evaluate(r, meta))
+		return
+	}
+
+	if *FlagPrompt != "" || *FlagREPL || *FlagServe != "" || *FlagScore {
+		m := NewFilteredWithMeta(meta)
+		m.Add(0)
+		for _, v := range []rune(*FlagPrompt) {
+			m.Add(forward[v])
+		}
+		var items []Item
+		if *FlagLambda != 0 || *FlagProvenance > 0 {
+			// the vectors of the database are only comparable to the mixer of the readout if they were built with the same mixer
+			db, err := LoadMeta("db.bin")
+			if err != nil {
+				panic(err)
+			}
+			if !reflect.DeepEqual(db, meta) {
+				panic(fmt.Errorf("the metadata of db.bin %+v doesn't match the metadata of %s %+v", db, ReadoutFile, meta))
+			}
+			items = LoadItems("db.bin")
+		}
+		next := func(m Mix) []float32 {
+			current := m.Mix()
+			distribution := r.Distribution(current)
+			if *FlagLambda != 0 {
+				neighbors := Search(items, current, KNNNeighbors)
+				distribution = Interpolate(*FlagLambda, neighbors, KNN(neighbors, length), distribution)
+			}
+			return distribution
+		}
+		sampler := FlagSampler(256, 1)
+		if *FlagREPL {
+			NewREPL(m, next, forward, reverse, sampler, FlagText(reverse)).Run(os.Stdin, os.Stdout)
+			return
+		}
+		model := Model{Mix: m, Distribution: next, Forward: forward, Reverse: reverse}
+		if *FlagScore {
+			ScoreCandidates(model)
+			return
+		}
+		if *FlagServe != "" {
+			Serve(*FlagServe, NewServer(model, sampler, FlagText(reverse), *FlagConcurrency))
+			return
+		}
+		if *FlagBeam > 0 {
+			search := FlagBeamSearch(sampler.Length)
+			search.Print(search.Search(m, next, FlagText(reverse)), sampler.Samples)
+			return
+		}
+		for range sampler.Samples {
+			text := sampler.Generate(m.Copy(), next, FlagText(reverse))
+			text.Print()
+			ShowProvenance(items, m.Copy(), text)
+		}
+		return
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"io"
+	"os"
+
+	"github.com/pointlander/textus/mat"
+)
+
+const (
+	// CrossFile is the vector database of the cross mixer
+	CrossFile = "cross.bin"
+)
+
+// Mach8 is the mach 8 model, a vector database of the cross mixer over the text and character class streams
+func Mach8() {
+	file, err := Data.Open("books/100.txt.utf-8.bz2")
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	reader := bzip2.NewReader(file)
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		panic(err)
+	}
+
+	forward, reverse, code := make(map[rune]byte), make(map[byte]rune), byte(0)
+	for _, v := range string(data) {
+		if _, ok := forward[v]; !ok {
+			forward[v] = code
+			reverse[code] = v
+			code++
+			if code > 255 {
+				panic("not enough codes")
+			}
+		}
+	}
+	length := len(forward)
+
+	if *FlagBuild {
+		db, err := os.Create(CrossFile)
+		if err != nil {
+			panic(err)
+		}
+		defer db.Close()
+		err = SaveMeta(CrossFile, Meta{Mixer: FlagMixerConfig()})
+		if err != nil {
+			panic(err)
+		}
+		output := bufio.NewWriter(db)
+		err = WriteHeader(output)
+		if err != nil {
+			panic(err)
+		}
+		m := NewCrossWithConfig(FlagMixerConfig())
+		m.Add(0, 0)
+		for i, v := range []rune(string(data)) {
+			vector := m.Mix()
+			err := WriteItem(output, vector[:], forward[v], Source{Offset: uint32(i)})
+			if err != nil {
+				panic(err)
+			}
+			m.Add(forward[v], mat.Class(v))
+		}
+		err = output.Flush()
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	if *FlagPrompt != "" || *FlagREPL || *FlagServe != "" || *FlagScore {
+		meta, err := LoadMeta(CrossFile)
+		if err != nil {
+			panic(err)
+		}
+		items := LoadItems(CrossFile)
+		var m CrossMix = NewCrossWithConfig(meta.Mixer)
+		m.Add(0, 0)
+		prompt := []byte{}
+		for _, v := range *FlagPrompt {
+			m.Add(forward[v], mat.Class(v))
+			prompt = append(prompt, forward[v])
+		}
+		var suffix *Suffix
+		if *FlagExact {
+			symbols := make([]byte, 0, len(data))
+			for _, v := range string(data) {
+				symbols = append(symbols, forward[v])
+			}
+			suffix = NewSuffix(symbols, length)
+		}
+		mixer := func() Mix {
+			var mix Mix = &Crossed{Cross: m.Copy(), Reverse: reverse}
+			if suffix != nil {
+				mix = NewSuffixed(mix, suffix, prompt)
+			}
+			return mix
+		}
+		search := func(m Mix) ([]float32, []Neighbor) {
+			neighbors := Search(items, m.Mix(), KNNNeighbors)
+			distribution := KNN(neighbors, length)
+			if s, ok := m.(*Suffixed); ok {
+				distribution = s.Match().Blend(distribution)
+			}
+			return distribution, neighbors
+		}
+		next := func(m Mix) []float32 {
+			distribution, _ := search(m)
+			return distribution
+		}
+		sampler, copier := FlagSampler(256, 1), FlagCopier(items, length)
+		if *FlagREPL {
+			NewREPL(mixer(), next, forward, reverse, sampler, FlagText(reverse)).Run(os.Stdin, os.Stdout)
+			return
+		}
+		model := Model{Mix: mixer(), Distribution: next, Forward: forward, Reverse: reverse}
+		if *FlagScore {
+			ScoreCandidates(model)
+			return
+		}
+		if *FlagServe != "" {
+			Serve(*FlagServe, NewServer(model, sampler, FlagText(reverse), *FlagConcurrency))
+			return
+		}
+		for range sampler.Samples {
+			var text *Text
+			if copier != nil {
+				text = copier.Generate(sampler, mixer(), search, FlagText(reverse))
+			} else {
+				text = sampler.Generate(mixer(), next, FlagText(reverse))
+			}
+			text.Print()
+			ShowProvenance(items, &Crossed{Cross: m.Copy(), Reverse: reverse}, text)
+		}
+		if copier != nil {
+			copier.Print()
+		}
+		return
+	}
+}
+
+// Crossed adapts a cross mixer to a mixer, the class of a symbol is derived from its rune
+type Crossed struct {
+	Cross   CrossMix
+	Reverse map[byte]rune
+}
+
+// Copy copies the mixer
+func (c *Crossed) Copy() Mix {
+	return &Crossed{
+		Cross:   c.Cross.Copy(),
+		Reverse: c.Reverse,
+	}
+}
+
+// Add adds a symbol to the mixer
+func (c *Crossed) Add(s byte) {
+	c.Cross.Add(s, mat.Class(c.Reverse[s]))
+}
+
+// Mix mixes the histograms
+func (c *Crossed) Mix() []float32 {
+	mix := c.Cross.Mix()
+	return mix[:]
+}
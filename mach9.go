@@ -0,0 +1,118 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"compress/bzip2"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Mach9 is the mach 9 model, fills the middle between the prompt and the suffix with a forward and a backward vector database
+func Mach9() {
+	file, err := Data.Open("books/100.txt.utf-8.bz2")
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	reader := bzip2.NewReader(file)
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		panic(err)
+	}
+
+	forward, reverse, code := make(map[rune]byte), make(map[byte]rune), byte(0)
+	for _, v := range string(data) {
+		if _, ok := forward[v]; !ok {
+			forward[v] = code
+			reverse[code] = v
+			code++
+			if code > 255 {
+				panic("not enough codes")
+			}
+		}
+	}
+	length := len(forward)
+	encode := func(text string) []byte {
+		symbols := []byte{}
+		for _, v := range text {
+			symbols = append(symbols, forward[v])
+		}
+		return symbols
+	}
+
+	if *FlagBuild {
+		meta := Meta{Mixer: FlagMixerConfig(), Head: *FlagHead}
+		symbols := encode(string(data))
+		for _, db := range []struct {
+			Name    string
+			Symbols []byte
+		}{
+			{InfillFile, symbols},
+			{InfillReverseFile, Reversed(symbols)},
+		} {
+			err := SaveMeta(db.Name, meta)
+			if err != nil {
+				panic(err)
+			}
+			m := NewFilteredWithMeta(meta)
+			m.Add(0)
+			err = BuildItems(db.Name, m, db.Symbols, 0)
+			if err != nil {
+				panic(err)
+			}
+		}
+		return
+	}
+
+	// the interactive, serving and scoring modes continue the text with the forward database
+	if *FlagREPL || *FlagServe != "" || *FlagScore {
+		meta, err := LoadMeta(InfillFile)
+		if err != nil {
+			panic(err)
+		}
+		m := NewFilteredWithMeta(meta)
+		m.Add(0)
+		for _, s := range encode(*FlagPrompt) {
+			m.Add(s)
+		}
+		items := LoadItems(InfillFile)
+		next := func(m Mix) []float32 {
+			return KNN(Search(items, m.Mix(), KNNNeighbors), length)
+		}
+		sampler := FlagSampler(256, 1)
+		if *FlagREPL {
+			NewREPL(m, next, forward, reverse, sampler, FlagText(reverse)).Run(os.Stdin, os.Stdout)
+			return
+		}
+		model := Model{Mix: m, Distribution: next, Forward: forward, Reverse: reverse}
+		if *FlagScore {
+			ScoreCandidates(model)
+			return
+		}
+		Serve(*FlagServe, NewServer(model, sampler, FlagText(reverse), *FlagConcurrency))
+		return
+	}
+
+	if *FlagPrompt != "" {
+		meta, err := LoadMeta(InfillFile)
+		if err != nil {
+			panic(err)
+		}
+		f, b := NewFilteredWithMeta(meta), NewFilteredWithMeta(meta)
+		f.Add(0)
+		b.Add(0)
+		infill := NewInfill(LoadItems(InfillFile), LoadItems(InfillReverseFile),
+			encode(*FlagPrompt), encode(*FlagSuffix), f, b, reverse, length)
+		sampler := FlagSampler(16, 4)
+		for _, middle := range infill.Fill(sampler, FlagText(reverse)) {
+			fmt.Println("_______________________________________________________________")
+			fmt.Println(middle.Score(), middle.Forward, middle.Backward)
+			fmt.Printf("%s[%s]%s\n", *FlagPrompt, NewText(Stop{}, reverse).AddAll(middle.Symbols), *FlagSuffix)
+		}
+		return
+	}
+}
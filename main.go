@@ -11,14 +11,14 @@ import (
 	"fmt"
 	"io"
 	"math"
-	"math/rand"
 	"os"
 	"path"
+	"runtime"
 	"sort"
-)
+	"strings"
 
-// todo
-// calculate page rank of mixer stochastic tree searches
+	"github.com/pointlander/textus/mat"
+)
 
 const (
 	// VectorSize is the size of a vector
@@ -59,6 +59,92 @@ var (
 	FlagBuild = flag.Bool("build", false, "build the bin file")
 	// FlagCompress compress the bin file
 	FlagCompress = flag.Bool("compress", false, "compress the bin file")
+	// FlagEval evaluate the model
+	FlagEval = flag.Bool("eval", false, "evaluate the model")
+	// FlagLambda the knn interpolation weight
+	FlagLambda = flag.Float64("lambda", 0, "knn interpolation weight, negative adapts the weight to the neighbor distance")
+	// FlagWindows the histogram windows of the mixer
+	FlagWindows = flag.String("windows", "", "comma separated histogram windows of the mixer")
+	// FlagOrder the order of the markov model of the mixer
+	FlagOrder = flag.Int("order", -1, "order of the markov model of the mixer")
+	// FlagRates the damping factors of the cdfs of the mixer
+	FlagRates = flag.String("rates", "", "comma separated damping factors of the cdfs of the mixer")
+	// FlagHalfLives the half-lives of the decayed histograms of the mixer
+	FlagHalfLives = flag.String("halflives", "", "comma separated half-lives of the decayed histograms of the mixer")
+	// FlagDecay use the decayed mixer
+	FlagDecay = flag.Bool("decay", false, "use the exponentially decayed histogram mixer")
+	// FlagHead the learned attention head of the mixer
+	FlagHead = flag.String("head", "", "learned attention head file of the mixer")
+	// FlagTrainHead train the learned attention head
+	FlagTrainHead = flag.Bool("trainhead", false, "train the learned attention head")
+	// FlagStack the mixers fused by a stack
+	FlagStack = flag.String("stack", "", "comma separated mixers fused by a stack: filtered, mixer, basic, decayed or words")
+	// FlagConcat concatenates the outputs of the stack
+	FlagConcat = flag.Bool("concat", false, "concatenate the outputs of the stack instead of fusing them")
+	// FlagTemperature the sampling temperature
+	FlagTemperature = flag.Float64("temperature", 1, "sampling temperature, 0 is greedy")
+	// FlagTopK the number of most likely symbols sampled from
+	FlagTopK = flag.Int("topk", 0, "sample from the k most likely symbols, 0 is all of the symbols")
+	// FlagTopP the nucleus sampling probability
+	FlagTopP = flag.Float64("topp", 1, "sample from the most likely symbols with a total probability of p")
+	// FlagLength the number of symbols generated
+	FlagLength = flag.Int("length", 0, "number of symbols generated, 0 is the default of the model")
+	// FlagSamples the number of samples generated
+	FlagSamples = flag.Int("samples", 0, "number of samples generated, 0 is the default of the model")
+	// FlagSeed the seed of the sampler
+	FlagSeed = flag.Int64("seed", 1, "seed of the sampler")
+	// FlagBeam the width of the beam search
+	FlagBeam = flag.Int("beam", 0, "width of the beam search, 0 samples instead")
+	// FlagAlpha the length normalization of the beam search
+	FlagAlpha = flag.Float64("alpha", 1, "length normalization exponent of the beam search")
+	// FlagDuplicate the number of trailing symbols compared to prune duplicate beams
+	FlagDuplicate = flag.Int("duplicate", 16, "number of trailing symbols compared to prune duplicate beams, 0 compares all of the symbols")
+	// FlagMCTS the number of simulations of the tree search for each symbol
+	FlagMCTS = flag.Int("mcts", 0, "number of monte carlo tree search simulations for each symbol, 0 samples instead")
+	// FlagDepth the depth of the tree search
+	FlagDepth = flag.Int("depth", 8, "maximum depth of the monte carlo tree search simulations")
+	// FlagLine finish generation at the end of a line
+	FlagLine = flag.Bool("line", false, "finish generation at the end of a line")
+	// FlagParagraph finish generation at the end of a paragraph
+	FlagParagraph = flag.Bool("paragraph", false, "finish generation at the end of a paragraph")
+	// FlagStops the stop strings that finish generation
+	FlagStops Strings
+	// FlagCandidates the candidate continuations that are scored
+	FlagCandidates Strings
+	// FlagRepetition the repetition penalty
+	FlagRepetition = flag.Float64("repetition", 1, "divides the probability of recently generated symbols")
+	// FlagFrequency the frequency penalty
+	FlagFrequency = flag.Float64("frequency", 0, "penalizes recently generated symbols by how often they were generated")
+	// FlagPresence the presence penalty
+	FlagPresence = flag.Float64("presence", 0, "penalizes recently generated symbols")
+	// FlagNGram the size of the blocked n-grams
+	FlagNGram = flag.Int("ngram", 0, "blocks symbols that repeat a recently generated n-gram of this size, 0 is no blocking")
+	// FlagWindow the window of recently generated symbols
+	FlagWindow = flag.Int("window", 64, "number of recently generated symbols that are penalized")
+	// FlagConstraintExpression the regular expression that generated text must match
+	FlagConstraintExpression = flag.String("constraint", "", "regular expression that the generated text must match, for example [[:upper:]]+\\.")
+	// FlagGrammar the grammar that generated text must match
+	FlagGrammar = flag.String("grammar", "", "file of grammar rules that the generated text must match, one \"name = expression\" per line")
+	// FlagSuffix the text that follows the infilled middle
+	FlagSuffix = flag.String("suffix", "", "text that follows the middle filled in after the prompt")
+	// FlagProvenance the number of corpus positions shown for each generated symbol
+	FlagProvenance = flag.Int("provenance", 0, "number of corpus positions and their source text shown for each generated symbol, 0 is none")
+	// FlagCopy the similarity that starts copying the corpus
+	FlagCopy = flag.Float64("copy", 0, "similarity of a neighbor that starts copying the corpus that follows it, 0 is no copying")
+	// FlagVerify the similarity that continues copying the corpus
+	FlagVerify = flag.Float64("verify", .9, "similarity of the next corpus record to the mixer vector that continues copying")
+	// FlagExact blends the longest exact match of the context into the neighbor distribution
+	FlagExact = flag.Bool("exact", false, "blends the symbols that follow the longest exact match of the context in the corpus into the neighbor distribution")
+	// FlagREPL interactive mode
+	FlagREPL = flag.Bool("repl", false, "reads prompts and commands interactively after loading the model")
+	// FlagServe the address of the http server
+	FlagServe = flag.String("serve", "", "address the http server listens on after loading the model, for example :8080")
+	// FlagConcurrency the maximum number of concurrent requests
+	FlagConcurrency = flag.Int("concurrency", runtime.NumCPU(), "maximum number of requests the http server handles at a time")
+	// FlagScore scoring mode
+	FlagScore = flag.Bool("score", false, "scores the candidate continuations of the prompt instead of generating")
+	// FlagTotal ranks the scored candidates by total log probability
+	FlagTotal = flag.Bool("total", false, "ranks the scored candidates by their total log probability instead of the mean log probability of their characters")
 	// FlagMach1 mach 1 mode
 	FlagMach1 = flag.Bool("mach1", false, "mach 1 model")
 	// FlagMach2 mach 2 model
@@ -69,6 +155,14 @@ var (
 	FlagMach4 = flag.Bool("mach4", false, "mach 4 model")
 	// FlagMach5 mach 5 model
 	FlagMach5 = flag.Bool("mach5", false, "mach 5 model")
+	// FlagMach6 mach 6 model
+	FlagMach6 = flag.Bool("mach6", false, "mach 6 model")
+	// FlagMach7 mach 7 model
+	FlagMach7 = flag.Bool("mach7", false, "mach 7 model")
+	// FlagMach8 mach 8 model
+	FlagMach8 = flag.Bool("mach8", false, "mach 8 model")
+	// FlagMach9 mach 9 model
+	FlagMach9 = flag.Bool("mach9", false, "mach 9 model")
 )
 
 func dot(a *[InputSize]float32, b []float32) float64 {
@@ -79,6 +173,15 @@ func dot(a *[InputSize]float32, b []float32) float64 {
 	return sum
 }
 
+// FlagMixerConfig is the mixer configuration selected by the flags
+func FlagMixerConfig() MixerConfig {
+	config, err := NewMixerConfig(*FlagWindows, *FlagOrder, *FlagRates, *FlagHalfLives)
+	if err != nil {
+		panic(err)
+	}
+	return config
+}
+
 // L2 is the L2 norm
 func L2(a, b []float64) float64 {
 	c := 0.0
@@ -89,7 +192,48 @@ func L2(a, b []float64) float64 {
 	return c
 }
 
+// FlagStackNames are the names of the mixers of the stack selected by the flags
+func FlagStackNames() []string {
+	if *FlagStack == "" {
+		return nil
+	}
+	names := strings.Split(*FlagStack, ",")
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+	return names
+}
+
+// newBasic makes the mixer of the basic model and returns its markov model
+func newBasic(meta Meta, classes []byte) (Mix, Markov) {
+	if len(meta.Stack) > 0 {
+		m := NewStackWithMeta(meta, classes)
+		return m, m.Markov
+	}
+	if meta.Decayed {
+		m := NewDecayedWithConfig(256, meta.Mixer)
+		return m, m.Markov
+	}
+	m := NewBasicWithConfig(256, meta.Mixer)
+	return m, m.Markov
+}
+
+// markovOf is the markov model of a mixer of the basic model
+func markovOf(m Mix) Markov {
+	switch m := m.(type) {
+	case *Stack:
+		return m.Markov
+	case *Decayed:
+		return m.Markov
+	case *Basic:
+		return m.Markov
+	}
+	panic(fmt.Errorf("%T is not a mixer of the basic model", m))
+}
+
 func main() {
+	flag.Var(&FlagStops, "stop", "stop string that finishes generation with go escape sequences, can be repeated")
+	flag.Var(&FlagCandidates, "candidate", "candidate continuation of the prompt that is scored with go escape sequences, can be repeated, standard input is read if there are none")
 	flag.Parse()
 
 	if *FlagMach1 {
@@ -117,6 +261,26 @@ func main() {
 		return
 	}
 
+	if *FlagMach6 {
+		Mach6()
+		return
+	}
+
+	if *FlagMach7 {
+		Mach7()
+		return
+	}
+
+	if *FlagMach8 {
+		Mach8()
+		return
+	}
+
+	if *FlagMach9 {
+		Mach9()
+		return
+	}
+
 	file, err := Data.Open("books/100.txt.utf-8.bz2")
 	if err != nil {
 		panic(err)
@@ -148,12 +312,20 @@ func main() {
 	}
 	if *FlagBuild {
 		//model := make(map[Context][]Vector)
-		m := NewBasic(256)
+		meta := Meta{Mixer: FlagMixerConfig(), Decayed: *FlagDecay, Stack: FlagStackNames(), Concat: *FlagConcat}
+		if meta.Concat && len(meta.Stack) == 0 {
+			panic("concat needs a stack")
+		}
+		err := SaveMeta("model", meta)
+		if err != nil {
+			panic(err)
+		}
+		m, markov := newBasic(meta, mat.Classes(reverse))
 		m.Add(0)
 		buffer32, buffer8 := make([]byte, 4), make([]byte, 1)
 		for _, v := range string(data) {
 			vector := m.Mix()
-			context := Context{m.Markov[0], m.Markov[1]}
+			context := Context{markov[0], markov[1]}
 			err := os.MkdirAll(path.Join("model", fmt.Sprintf("%d", context[0])), 0750)
 			if err != nil {
 				panic(err)
@@ -203,116 +375,133 @@ func main() {
 		return
 	}
 
-	if *FlagPrompt != "" {
-		rng := rand.New(rand.NewSource(1))
+	modes := *FlagREPL || *FlagServe != "" || *FlagScore
+	if *FlagPrompt != "" || modes {
+		meta, err := LoadMeta("model")
+		if err != nil {
+			panic(err)
+		}
+		sampler := FlagSampler(33, 33)
 		type Sample struct {
-			Sample      string
+			Sample      *Text
 			Probability float32
 		}
 		samples := []Sample{}
-		fmt.Println(*FlagPrompt)
-		for range 33 {
-			m := NewBasic(256)
-			txt := []rune(*FlagPrompt)
-			for _, v := range txt {
-				m.Add(forward[v])
-			}
-			sample := Sample{}
-			for range 33 {
-				current := m.Mix()
-				context := Context{m.Markov[0], m.Markov[1]}
-				//max, symbol := float32(0.0), byte(0)
-				symbol := byte(0)
-				histogram, count := make([]float32, length), float32(0.0)
-				name := path.Join("model", fmt.Sprintf("%d", context[0]), fmt.Sprintf("%d", context[1]))
-				input, err := os.Open(name)
-				if err == nil {
-					buffer, vector := [ItemSize]byte{}, [InputSize]float32{}
-					for {
-						n, err := input.Read(buffer[:])
-						if err == io.EOF {
-							err := input.Close()
-							if err != nil {
-								panic(err)
-							}
-							break
-						} else if err != nil {
+		// the vectors are as wide as the output of the mixer
+		start, _ := newBasic(meta, mat.Classes(reverse))
+		width := len(start.Mix())
+		if !modes {
+			fmt.Println(*FlagPrompt)
+		}
+		next := func(m Mix) []float32 {
+			t := m.(*Tally)
+			current, markov := t.Mix(), markovOf(t.Mixer)
+			context := Context{markov[0], markov[1]}
+			//max, symbol := float32(0.0), byte(0)
+			histogram, count := make([]float32, length), float32(0.0)
+			name := path.Join("model", fmt.Sprintf("%d", context[0]), fmt.Sprintf("%d", context[1]))
+			input, err := os.Open(name)
+			if err == nil {
+				buffer, vector := make([]byte, 4*width+1), make([]float32, width)
+				for {
+					n, err := io.ReadFull(input, buffer)
+					if err == io.EOF {
+						err := input.Close()
+						if err != nil {
 							panic(err)
 						}
-						if n != len(buffer) {
-							panic("not all bytes read")
-						}
-						for j := range vector {
-							value := uint32(0)
-							for k := 0; k < 4; k++ {
-								value <<= 8
-								value |= uint32(buffer[j*4+3-k])
-							}
-							vector[j] = math.Float32frombits(value)
+						break
+					} else if err != nil {
+						panic(err)
+					}
+					if n != len(buffer) {
+						panic("not all bytes read")
+					}
+					for j := range vector {
+						value := uint32(0)
+						for k := 0; k < 4; k++ {
+							value <<= 8
+							value |= uint32(buffer[j*4+3-k])
 						}
-						a, s := CS(vector[:], current[:]), buffer[len(buffer)-1]
-						/*if a > max {
-							max, symbol = a, s
-						}*/
-						histogram[s] += a
-						count += a
+						vector[j] = math.Float32frombits(value)
 					}
-				} else {
-					for i := range length {
-						name := path.Join("model", fmt.Sprintf("%d", context[0]), fmt.Sprintf("%d", i))
-						input, err := os.Open(name)
-						if err == nil {
-							buffer, vector := [ItemSize]byte{}, [InputSize]float32{}
-							for {
-								n, err := input.Read(buffer[:])
-								if err == io.EOF {
-									err := input.Close()
-									if err != nil {
-										panic(err)
-									}
-									break
-								} else if err != nil {
+					a, s := CS(vector[:], current[:]), buffer[len(buffer)-1]
+					/*if a > max {
+						max, symbol = a, s
+					}*/
+					histogram[s] += a
+					count += a
+				}
+			} else {
+				for i := range length {
+					name := path.Join("model", fmt.Sprintf("%d", context[0]), fmt.Sprintf("%d", i))
+					input, err := os.Open(name)
+					if err == nil {
+						buffer, vector := make([]byte, 4*width+1), make([]float32, width)
+						for {
+							n, err := io.ReadFull(input, buffer)
+							if err == io.EOF {
+								err := input.Close()
+								if err != nil {
 									panic(err)
 								}
-								if n != len(buffer) {
-									panic("not all bytes read")
-								}
-								for j := range vector {
-									value := uint32(0)
-									for k := 0; k < 4; k++ {
-										value <<= 8
-										value |= uint32(buffer[j*4+3-k])
-									}
-									vector[j] = math.Float32frombits(value)
+								break
+							} else if err != nil {
+								panic(err)
+							}
+							if n != len(buffer) {
+								panic("not all bytes read")
+							}
+							for j := range vector {
+								value := uint32(0)
+								for k := 0; k < 4; k++ {
+									value <<= 8
+									value |= uint32(buffer[j*4+3-k])
 								}
-								a, s := CS(vector[:], current[:]), buffer[len(buffer)-1]
-								/*if a > max {
-									max, symbol = a, s
-								}*/
-								histogram[s] += a
-								count += a
+								vector[j] = math.Float32frombits(value)
 							}
+							a, s := CS(vector[:], current[:]), buffer[len(buffer)-1]
+							/*if a > max {
+								max, symbol = a, s
+							}*/
+							histogram[s] += a
+							count += a
 						}
 					}
 				}
-				for i, c := range histogram {
-					histogram[i] = c / count
-				}
-				sum, selected := float32(0.0), rng.Float32()
-				for i, c := range histogram {
-					sum += c
-					if selected < sum {
-						symbol = byte(i)
-						sample.Sample += fmt.Sprintf("%c", reverse[byte(i)])
-						sample.Probability += c
-						break
-					}
-				}
-				//fmt.Printf("%c %d\n", reverse[symbol], reverse[symbol])
-				//txt = append(txt, reverse[symbol])
-				m.Add(symbol)
 			}
-			samples = append(samples, sample)
+			for i, c := range histogram {
+				histogram[i] = c / count
+			}
+			t.Votes = histogram
+			return histogram
+		}
+		// the mixer of the modes is a tally because the distribution sets its votes
+		if modes {
+			m, _ := newBasic(meta, mat.Classes(reverse))
+			for _, v := range *FlagPrompt {
+				m.Add(forward[v])
+			}
+			if *FlagREPL {
+				NewREPL(&Tally{Mixer: m}, next, forward, reverse, sampler, FlagText(reverse)).Run(os.Stdin, os.Stdout)
+				return
+			}
+			model := Model{Mix: &Tally{Mixer: m}, Distribution: next, Forward: forward, Reverse: reverse}
+			if *FlagScore {
+				ScoreCandidates(model)
+				return
+			}
+			Serve(*FlagServe, NewServer(model, sampler, FlagText(reverse), *FlagConcurrency))
+			return
+		}
+		for range sampler.Samples {
+			m, _ := newBasic(meta, mat.Classes(reverse))
+			txt := []rune(*FlagPrompt)
+			for _, v := range txt {
+				m.Add(forward[v])
+			}
+			text, tally := sampler.Decode(&Tally{Mixer: m}, next, FlagText(reverse))
+			samples = append(samples, Sample{Sample: text, Probability: float32(tally.(*Tally).Sum)})
 		}
 
 		sort.Slice(samples, func(i, j int) bool {
@@ -321,7 +510,7 @@ func main() {
 		for _, sample := range samples {
 			fmt.Println("_______________________________________________________________")
 			fmt.Println(sample.Probability)
-			fmt.Println(sample.Sample)
+			sample.Sample.Print()
 		}
 		return
 	}
@@ -0,0 +1,324 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mat
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrCorrupt is returned when binary mixer state can't be decoded
+var ErrCorrupt = errors.New("corrupt mixer state")
+
+// writer appends values to a binary encoding
+type writer []byte
+
+func (w *writer) Int(v int) {
+	*w = binary.AppendVarint(*w, int64(v))
+}
+
+func (w *writer) Bool(v bool) {
+	if v {
+		*w = append(*w, 1)
+	} else {
+		*w = append(*w, 0)
+	}
+}
+
+func (w *writer) Bytes(v []byte) {
+	w.Int(len(v))
+	*w = append(*w, v...)
+}
+
+func (w *writer) Ints(v []int) {
+	w.Int(len(v))
+	for _, x := range v {
+		w.Int(x)
+	}
+}
+
+func (w *writer) Int64s(v []int64) {
+	w.Int(len(v))
+	for _, x := range v {
+		*w = binary.AppendVarint(*w, x)
+	}
+}
+
+func (w *writer) Uint16s(v []uint16) {
+	w.Int(len(v))
+	for _, x := range v {
+		*w = binary.LittleEndian.AppendUint16(*w, x)
+	}
+}
+
+func (w *writer) Marshal(v encoding.BinaryMarshaler) error {
+	data, err := v.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	w.Bytes(data)
+	return nil
+}
+
+// reader reads values from a binary encoding, the first error is kept
+type reader struct {
+	Data []byte
+	Err  error
+}
+
+func (r *reader) Int() int {
+	if r.Err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.Data)
+	if n <= 0 {
+		r.Err = ErrCorrupt
+		return 0
+	}
+	r.Data = r.Data[n:]
+	return int(v)
+}
+
+// Len reads a length that is checked against the remaining data
+func (r *reader) Len(size int) int {
+	n := r.Int()
+	if n < 0 || n*size > len(r.Data) {
+		if r.Err == nil {
+			r.Err = ErrCorrupt
+		}
+		return 0
+	}
+	return n
+}
+
+func (r *reader) Bool() bool {
+	if r.Err != nil {
+		return false
+	}
+	if len(r.Data) == 0 {
+		r.Err = ErrCorrupt
+		return false
+	}
+	v := r.Data[0]
+	r.Data = r.Data[1:]
+	return v != 0
+}
+
+func (r *reader) Bytes() []byte {
+	n := r.Len(1)
+	v := make([]byte, n)
+	copy(v, r.Data)
+	r.Data = r.Data[n:]
+	return v
+}
+
+func (r *reader) Ints() []int {
+	v := make([]int, r.Len(1))
+	for i := range v {
+		v[i] = r.Int()
+	}
+	return v
+}
+
+func (r *reader) Int64s() []int64 {
+	v := make([]int64, r.Len(1))
+	for i := range v {
+		v[i] = int64(r.Int())
+	}
+	return v
+}
+
+func (r *reader) Uint16s() []uint16 {
+	v := make([]uint16, r.Len(2))
+	for i := range v {
+		v[i] = binary.LittleEndian.Uint16(r.Data)
+		r.Data = r.Data[2:]
+	}
+	return v
+}
+
+func (r *reader) Unmarshal(v encoding.BinaryUnmarshaler) {
+	data := r.Bytes()
+	if r.Err != nil {
+		return
+	}
+	r.Err = v.UnmarshalBinary(data)
+}
+
+// Close checks that all of the data was read
+func (r *reader) Close() error {
+	if r.Err == nil && len(r.Data) != 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrCorrupt, len(r.Data))
+	}
+	return r.Err
+}
+
+// MarshalBinary encodes the cdf
+func (c *CDF16) MarshalBinary() ([]byte, error) {
+	var w writer
+	w.Int(c.Size)
+	w.Int(c.Rate)
+	w.Bool(c.Verify)
+	w.Uint16s(c.Model)
+	return w, nil
+}
+
+// UnmarshalBinary decodes and validates the cdf, the mixin tables are rebuilt
+func (c *CDF16) UnmarshalBinary(data []byte) error {
+	r := reader{Data: data}
+	size, rate, verify, model := r.Int(), r.Int(), r.Bool(), r.Uint16s()
+	if err := r.Close(); err != nil {
+		return err
+	}
+	if size != 256 || len(model) != size+1 {
+		return fmt.Errorf("%w: cdf size %d with %d entries", ErrCorrupt, size, len(model))
+	}
+	if rate < 1 || rate > CDF16Fixed {
+		return fmt.Errorf("%w: cdf rate %d", ErrCorrupt, rate)
+	}
+	if model[0] != 0 || model[size] != CDF16Scale {
+		return fmt.Errorf("%w: cdf from %d to %d", ErrCorrupt, model[0], model[size])
+	}
+	for i := 1; i < len(model); i++ {
+		if model[i] < model[i-1] {
+			return fmt.Errorf("%w: cdf decreases at %d", ErrCorrupt, i)
+		}
+	}
+	*c = *NewCDF16(verify)(size, rate).(*CDF16)
+	c.Model = model
+	return nil
+}
+
+// MarshalBinary encodes the histogram
+func (h Histogram) MarshalBinary() ([]byte, error) {
+	var w writer
+	w.Ints(h.Vector)
+	w.Bytes(h.Buffer)
+	w.Int(h.Index)
+	w.Int(h.Size)
+	return w, nil
+}
+
+// UnmarshalBinary decodes the histogram
+func (h *Histogram) UnmarshalBinary(data []byte) error {
+	r := reader{Data: data}
+	vector, buffer, index, size := r.Ints(), r.Bytes(), r.Int(), r.Int()
+	if err := r.Close(); err != nil {
+		return err
+	}
+	if len(buffer) != size || index < 0 || index >= size || len(vector) == 0 {
+		return fmt.Errorf("%w: histogram size %d index %d", ErrCorrupt, size, index)
+	}
+	for _, s := range buffer {
+		if int(s) >= len(vector) {
+			return fmt.Errorf("%w: histogram symbol %d", ErrCorrupt, s)
+		}
+	}
+	h.Vector, h.Buffer, h.Index, h.Size = vector, buffer, index, size
+	return nil
+}
+
+// marshalHistograms encodes the state shared by the histogram mixers
+func marshalHistograms(markov Markov, histograms []Histogram, gram Gram, length int) ([]byte, error) {
+	var w writer
+	w.Bytes(markov)
+	w.Int(len(histograms))
+	for _, h := range histograms {
+		if err := w.Marshal(h); err != nil {
+			return nil, err
+		}
+	}
+	w.Int(gram.Rows)
+	w.Int64s(gram.Counts)
+	w.Int64s(gram.Sums)
+	w.Int(length)
+	return w, nil
+}
+
+// unmarshalHistograms decodes the state shared by the histogram mixers
+func unmarshalHistograms(data []byte) (Markov, []Histogram, Gram, int, error) {
+	r := reader{Data: data}
+	markov := Markov(r.Bytes())
+	histograms := make([]Histogram, r.Len(1))
+	for i := range histograms {
+		r.Unmarshal(&histograms[i])
+	}
+	gram := Gram{Rows: r.Int(), Counts: r.Int64s(), Sums: r.Int64s()}
+	length := r.Int()
+	if err := r.Close(); err != nil {
+		return nil, nil, Gram{}, 0, err
+	}
+	rows := len(histograms)
+	if len(markov) == 0 || gram.Rows != rows || len(gram.Counts) != rows*rows || len(gram.Sums) != rows {
+		return nil, nil, Gram{}, 0, fmt.Errorf("%w: %d histograms", ErrCorrupt, rows)
+	}
+	for _, h := range histograms {
+		if len(h.Vector) != length {
+			return nil, nil, Gram{}, 0, fmt.Errorf("%w: histogram length %d", ErrCorrupt, len(h.Vector))
+		}
+	}
+	return markov, histograms, gram, length, nil
+}
+
+// MarshalBinary encodes the mixer
+func (m Mixer[T]) MarshalBinary() ([]byte, error) {
+	return marshalHistograms(m.Markov, m.Histograms, m.Gram, m.Length)
+}
+
+// UnmarshalBinary decodes the mixer
+func (m *Mixer[T]) UnmarshalBinary(data []byte) (err error) {
+	m.Markov, m.Histograms, m.Gram, m.Length, err = unmarshalHistograms(data)
+	return err
+}
+
+// MarshalBinary encodes the basic mixer
+func (b Basic[T]) MarshalBinary() ([]byte, error) {
+	return marshalHistograms(b.Markov, b.Histograms, b.Gram, b.Length)
+}
+
+// UnmarshalBinary decodes the basic mixer
+func (b *Basic[T]) UnmarshalBinary(data []byte) (err error) {
+	b.Markov, b.Histograms, b.Gram, b.Length, err = unmarshalHistograms(data)
+	return err
+}
+
+// MarshalBinary encodes the filtered counter, the learned attention head is not encoded
+func (f Filtered[T]) MarshalBinary() ([]byte, error) {
+	var w writer
+	w.Bytes(f.Markov)
+	w.Int(len(f.Filters))
+	for _, filter := range f.Filters {
+		marshaler, ok := filter.(encoding.BinaryMarshaler)
+		if !ok {
+			return nil, fmt.Errorf("%T can't be marshaled", filter)
+		}
+		if err := w.Marshal(marshaler); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// UnmarshalBinary decodes the filtered counter, the learned attention head is kept
+func (f *Filtered[T]) UnmarshalBinary(data []byte) error {
+	r := reader{Data: data}
+	markov := Markov(r.Bytes())
+	filters := make([]Filtered16, r.Len(1))
+	for i := range filters {
+		cdf := &CDF16{}
+		r.Unmarshal(cdf)
+		filters[i] = cdf
+	}
+	if err := r.Close(); err != nil {
+		return err
+	}
+	if len(markov) == 0 {
+		return fmt.Errorf("%w: empty markov model", ErrCorrupt)
+	}
+	f.Markov, f.Filters = markov, filters
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mat
+
+import (
+	"encoding"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBinary(t *testing.T) {
+	marshal[float32](t)
+	marshal[float64](t)
+}
+
+func marshal[T Float](t *testing.T) {
+	type state interface {
+		Mix[T]
+		encoding.BinaryMarshaler
+		encoding.BinaryUnmarshaler
+	}
+	mixers := []struct {
+		Name  string
+		Mixer state
+		Empty state
+	}{
+		{"filtered", NewFiltered[T](), &Filtered[T]{}},
+		{"mixer", NewMixer[T](32), &Mixer[T]{}},
+		{"basic", NewBasic[T](32), &Basic[T]{}},
+	}
+	for _, m := range mixers {
+		for i := range 100 {
+			m.Mixer.Add(byte(i*7) % 32)
+		}
+		data, err := m.Mixer.MarshalBinary()
+		if err != nil {
+			t.Fatal(m.Name, err)
+		}
+		err = m.Empty.UnmarshalBinary(data)
+		if err != nil {
+			t.Fatal(m.Name, err)
+		}
+		if !reflect.DeepEqual(m.Mixer, m.Empty) {
+			t.Fatalf("%s: the state was not restored", m.Name)
+		}
+		for i := range 10 {
+			m.Mixer.Add(byte(i))
+			m.Empty.Add(byte(i))
+		}
+		near(t, m.Name, 0, m.Mixer.Mix(), m.Empty.Mix())
+
+		err = m.Empty.UnmarshalBinary(data[:len(data)-1])
+		if !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%s: %v is not corrupt", m.Name, err)
+		}
+		err = m.Empty.UnmarshalBinary(append(data, 0))
+		if !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%s: %v is not corrupt", m.Name, err)
+		}
+	}
+}
+
+func TestCDF16Corrupt(t *testing.T) {
+	cdf := NewCDF16(false)(256, CDF16Rate).(*CDF16)
+	cdf.Update(3)
+	data, err := cdf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (&CDF16{}).UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		Name   string
+		Change func(c *CDF16)
+	}{
+		{"rate 0", func(c *CDF16) { c.Rate = 0 }},
+		{"rate over the shift", func(c *CDF16) { c.Rate = CDF16Fixed + 1 }},
+		{"start", func(c *CDF16) { c.Model[0] = 1 }},
+		{"scale", func(c *CDF16) { c.Model[256] = CDF16Scale - 1 }},
+		{"decreasing", func(c *CDF16) { c.Model[5] = c.Model[4] - 1 }},
+	} {
+		c := cdf.Copy().(*CDF16)
+		test.Change(c)
+		data, err := c.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = (&CDF16{}).UnmarshalBinary(data)
+		if !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%s: %v is not corrupt", test.Name, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mat
+
+import (
+	"fmt"
+	"math"
+)
+
+// MixerConfig is the configuration of a mixer
+type MixerConfig struct {
+	// Windows are the sizes of the histogram windows
+	Windows []int
+	// Order is the order of the markov model
+	Order int
+	// Rates are the damping factors of the cdfs
+	Rates []int
+	// HalfLives are the half-lives of the decayed histograms
+	HalfLives []float64
+}
+
+// DefaultMixerConfig is the default mixer configuration
+func DefaultMixerConfig() MixerConfig {
+	config := MixerConfig{
+		Order: Order,
+	}
+	for i := range Size {
+		config.Windows = append(config.Windows, 1<<i)
+		config.Rates = append(config.Rates, i+1)
+		config.HalfLives = append(config.HalfLives, math.Pow(4, float64(i)))
+	}
+	return config
+}
+
+// Check checks the mixer configuration
+func (c MixerConfig) Check() error {
+	if c.Order < 0 {
+		return fmt.Errorf("order %d is negative", c.Order)
+	}
+	for _, window := range c.Windows {
+		if window < 1 {
+			return fmt.Errorf("window %d is less than 1", window)
+		}
+	}
+	for _, halfLife := range c.HalfLives {
+		if halfLife <= 0 {
+			return fmt.Errorf("half-life %f is not positive", halfLife)
+		}
+	}
+	for _, rate := range c.Rates {
+		if rate < 1 || rate > CDF16Fixed {
+			return fmt.Errorf("rate %d is not between 1 and %d", rate, CDF16Fixed)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mat
+
+import (
+	"unicode"
+)
+
+// Class is the character class of a rune, it is the second stream of the cross mixer
+func Class(r rune) byte {
+	switch {
+	case unicode.IsUpper(r):
+		return 1
+	case unicode.IsLetter(r):
+		return 2
+	case unicode.IsDigit(r):
+		return 3
+	case r == '\n':
+		return 4
+	case unicode.IsSpace(r):
+		return 5
+	case unicode.IsPunct(r):
+		return 6
+	}
+	return 0
+}
+
+// Cross mixes two aligned symbol streams, the rows of the first stream attend across the rows of both streams
+type Cross[T Float] struct {
+	Source *Filtered[T]
+	Target *Filtered[T]
+}
+
+// NewCross makes a new cross mixer
+func NewCross[T Float]() *Cross[T] {
+	return NewCrossWithConfig[T](DefaultMixerConfig())
+}
+
+// NewCrossWithConfig makes a new cross mixer with a configuration
+func NewCrossWithConfig[T Float](config MixerConfig) *Cross[T] {
+	return &Cross[T]{
+		Source: NewFilteredWithConfig[T](config),
+		Target: NewFilteredWithConfig[T](config),
+	}
+}
+
+// Copy copies the cross mixer
+func (c Cross[T]) Copy() CrossMix[T] {
+	return &Cross[T]{
+		Source: c.Source.Copy().(*Filtered[T]),
+		Target: c.Target.Copy().(*Filtered[T]),
+	}
+}
+
+// Add adds a symbol to each of the streams
+func (c *Cross[T]) Add(source, target byte) {
+	c.Source.Add(source)
+	c.Target.Add(target)
+}
+
+// Mix computes the cross attention of the source rows over the source and target rows
+func (c Cross[T]) Mix() [InputSize]T {
+	queries, targets := c.Source.Rows(), c.Target.Rows()
+	keys := NewMatrix[T](InputSize, queries.Rows+targets.Rows, append(append([]T{}, queries.Data...), targets.Data...)...)
+	values, output := make([]T, keys.Rows), [InputSize]T{}
+	for i := 0; i < queries.Rows; i++ {
+		query := queries.Data[i*queries.Cols : (i+1)*queries.Cols]
+		for j := 0; j < keys.Rows; j++ {
+			values[j] = Dot(query, keys.Data[j*keys.Cols:(j+1)*keys.Cols])
+		}
+		Softmax(values)
+		for j, a := range values {
+			for k, v := range keys.Data[j*keys.Cols : (j+1)*keys.Cols] {
+				output[k] += a * v
+			}
+		}
+	}
+	Normalize(output[:])
+	return output
+}
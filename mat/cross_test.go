@@ -0,0 +1,47 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mat
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCross(t *testing.T) {
+	cross[float32](t)
+	cross[float64](t)
+}
+
+func cross[T Float](t *testing.T) {
+	text := "Hello World 1\nhello world 2\n"
+	var m CrossMix[T] = NewCross[T]()
+	for _, v := range text {
+		m.Add(byte(v), Class(v))
+	}
+	cp := m.Copy()
+	x := m.Mix()
+	sum := 0.0
+	for _, v := range x {
+		sum += float64(v) * float64(v)
+	}
+	if math.Abs(sum-1) > 1e-4 {
+		t.Fatalf("%f is not 1", sum)
+	}
+	m.Add('!', Class('!'))
+	if y := cp.Mix(); y != x {
+		t.Fatal("copy was modified")
+	}
+	if y := m.Mix(); y == x {
+		t.Fatal("mix did not change")
+	}
+
+	class := NewCross[T]()
+	for _, v := range text {
+		class.Add(byte(v), Class('a'))
+	}
+	if y := class.Mix(); y == x {
+		t.Fatal("the second stream is ignored")
+	}
+}
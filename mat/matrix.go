@@ -2,11 +2,14 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package mat64
+// Package mat implements the matrices and mixers for float32 and float64
+package mat
 
 import (
 	"fmt"
 	"math"
+
+	"github.com/pointlander/textus/vector"
 )
 
 const (
@@ -19,66 +22,87 @@ const (
 	S = 1.0 - 1e38*math.SmallestNonzeroFloat32
 )
 
-// Matrix is a float64 matrix
-type Matrix struct {
+// Float is the element type of a matrix
+type Float interface {
+	~float32 | ~float64
+}
+
+// Dot computes the dot product of two vectors, float32 vectors use SIMD
+func Dot[T Float](a, b []T) T {
+	if x, ok := any(a).([]float32); ok {
+		return T(vector.Dot(x, any(b).([]float32)))
+	}
+	sum := T(0.0)
+	for i, value := range a {
+		sum += value * b[i]
+	}
+	return sum
+}
+
+func sqrt[T Float](a T) T {
+	return T(math.Sqrt(float64(a)))
+}
+
+func exp[T Float](a T) T {
+	return T(math.Exp(float64(a)))
+}
+
+func log[T Float](a T) T {
+	return T(math.Log(float64(a)))
+}
+
+// Matrix is a float32 or float64 matrix
+type Matrix[T Float] struct {
 	Cols int
 	Rows int
-	Data []float64
+	Data []T
 }
 
-// NewMatrix creates a new float64 matrix
-func NewMatrix(cols, rows int, data ...float64) Matrix {
+// NewMatrix creates a new matrix
+func NewMatrix[T Float](cols, rows int, data ...T) Matrix[T] {
 	if data == nil {
-		data = make([]float64, 0, cols*rows)
+		data = make([]T, 0, cols*rows)
 	}
-	return Matrix{
+	return Matrix[T]{
 		Cols: cols,
 		Rows: rows,
 		Data: data,
 	}
 }
 
-func dot(a, b []float64) float64 {
-	sum := 0.0
-	for i, value := range a {
-		sum += value * b[i]
-	}
-	return sum
-}
-
 // MulT multiplies two matrices and computes the transpose
-func (m Matrix) MulT(n Matrix) Matrix {
+func (m Matrix[T]) MulT(n Matrix[T]) Matrix[T] {
 	if m.Cols != n.Cols {
 		panic(fmt.Errorf("%d != %d", m.Cols, n.Cols))
 	}
 	columns := m.Cols
-	o := Matrix{
+	o := Matrix[T]{
 		Cols: m.Rows,
 		Rows: n.Rows,
-		Data: make([]float64, 0, m.Rows*n.Rows),
+		Data: make([]T, 0, m.Rows*n.Rows),
 	}
 	lenn, lenm := len(n.Data), len(m.Data)
 	for i := 0; i < lenn; i += columns {
 		nn := n.Data[i : i+columns]
 		for j := 0; j < lenm; j += columns {
 			mm := m.Data[j : j+columns]
-			o.Data = append(o.Data, dot(mm, nn))
+			o.Data = append(o.Data, Dot(mm, nn))
 		}
 	}
 	return o
 }
 
-// Add adds two float64 matrices
-func (m Matrix) Add(n Matrix) Matrix {
+// Add adds two matrices
+func (m Matrix[T]) Add(n Matrix[T]) Matrix[T] {
 	lena, lenb := len(m.Data), len(n.Data)
 	if lena%lenb != 0 {
 		panic(fmt.Errorf("%d %% %d != 0", lena, lenb))
 	}
 
-	o := Matrix{
+	o := Matrix[T]{
 		Cols: m.Cols,
 		Rows: m.Rows,
-		Data: make([]float64, 0, m.Cols*m.Rows),
+		Data: make([]T, 0, m.Cols*m.Rows),
 	}
 	for i, value := range m.Data {
 		o.Data = append(o.Data, value+n.Data[i%lenb])
@@ -86,17 +110,17 @@ func (m Matrix) Add(n Matrix) Matrix {
 	return o
 }
 
-// Sub subtracts two float64 matrices
-func (m Matrix) Sub(n Matrix) Matrix {
+// Sub subtracts two matrices
+func (m Matrix[T]) Sub(n Matrix[T]) Matrix[T] {
 	lena, lenb := len(m.Data), len(n.Data)
 	if lena%lenb != 0 {
 		panic(fmt.Errorf("%d %% %d != 0", lena, lenb))
 	}
 
-	o := Matrix{
+	o := Matrix[T]{
 		Cols: m.Cols,
 		Rows: m.Rows,
-		Data: make([]float64, 0, m.Cols*m.Rows),
+		Data: make([]T, 0, m.Cols*m.Rows),
 	}
 	for i, value := range m.Data {
 		o.Data = append(o.Data, value-n.Data[i%lenb])
@@ -105,21 +129,21 @@ func (m Matrix) Sub(n Matrix) Matrix {
 }
 
 // Softmax calculates the softmax of the matrix rows
-func (m Matrix) Softmax(T float64) Matrix {
-	output := NewMatrix(m.Cols, m.Rows)
-	max := 0.0
+func (m Matrix[T]) Softmax(t T) Matrix[T] {
+	output := NewMatrix[T](m.Cols, m.Rows)
+	max := T(0.0)
 	for _, v := range m.Data {
-		v /= T
+		v /= t
 		if v > max {
 			max = v
 		}
 	}
 	s := max * S
 	for i := 0; i < len(m.Data); i += m.Cols {
-		sum := 0.0
-		values := make([]float64, m.Cols)
+		sum := T(0.0)
+		values := make([]T, m.Cols)
 		for j, value := range m.Data[i : i+m.Cols] {
-			values[j] = math.Exp(value/T - s)
+			values[j] = exp(value/t - s)
 			sum += values[j]
 		}
 		for _, value := range values {
@@ -130,12 +154,12 @@ func (m Matrix) Softmax(T float64) Matrix {
 }
 
 // Entropy calculates the entropy of the matrix rows
-func (m Matrix) Entropy() Matrix {
-	output := NewMatrix(m.Rows, 1)
+func (m Matrix[T]) Entropy() Matrix[T] {
+	output := NewMatrix[T](m.Rows, 1)
 	for i := 0; i < len(m.Data); i += m.Cols {
-		entropy := 0.0
+		entropy := T(0.0)
 		for _, value := range m.Data[i : i+m.Cols] {
-			entropy += value * math.Log(value)
+			entropy += value * log(value)
 		}
 		output.Data = append(output.Data, -entropy)
 	}
@@ -143,11 +167,11 @@ func (m Matrix) Entropy() Matrix {
 }
 
 // T tramsposes a matrix
-func (m Matrix) T() Matrix {
-	o := Matrix{
+func (m Matrix[T]) T() Matrix[T] {
+	o := Matrix[T]{
 		Cols: m.Rows,
 		Rows: m.Cols,
-		Data: make([]float64, 0, m.Cols*m.Rows),
+		Data: make([]T, 0, m.Cols*m.Rows),
 	}
 	for i := 0; i < m.Cols; i++ {
 		for j := 0; j < m.Rows; j++ {
@@ -158,31 +182,32 @@ func (m Matrix) T() Matrix {
 }
 
 // AddRow adds a row to a matrix
-func (m Matrix) AddRow(row []float64) Matrix {
+func (m Matrix[T]) AddRow(row []T) Matrix[T] {
 	if len(row) != m.Cols {
 		panic("incorrect number of columns")
 	}
-	o := Matrix{
+	o := Matrix[T]{
 		Cols: m.Cols,
 		Rows: m.Rows + 1,
-		Data: make([]float64, m.Cols*m.Rows),
+		Data: make([]T, m.Cols*m.Rows),
 	}
 	copy(o.Data, m.Data)
 	o.Data = append(o.Data, row...)
 	return o
 }
 
-func softmax(values []float64) {
-	max := 0.0
+// Softmax computes the softmax of values in place
+func Softmax[T Float](values []T) {
+	max := T(0.0)
 	for _, v := range values {
 		if v > max {
 			max = v
 		}
 	}
 	s := max * S
-	sum := 0.0
+	sum := T(0.0)
 	for j, value := range values {
-		values[j] = math.Exp(value - s)
+		values[j] = exp(value - s)
 		sum += values[j]
 	}
 	for j, value := range values {
@@ -191,38 +216,61 @@ func softmax(values []float64) {
 }
 
 // SelfAttention computes the self attention of Q, K, V
-func SelfAttention(input Matrix) []float64 {
-	values := make([]float64, input.Rows)
+func SelfAttention[T Float](input Matrix[T]) []T {
+	values := make([]T, input.Rows)
 	V := input.T()
-	output := make([]float64, input.Cols)
+	output := make([]T, input.Cols)
 	for i := 0; i < input.Rows; i++ {
 		K := input.Data[i*input.Cols : (i+1)*input.Cols]
 		for j := 0; j < input.Rows; j++ {
 			Q := input.Data[j*input.Cols : (j+1)*input.Cols]
-			values[j] = dot(K, Q)
+			values[j] = Dot(K, Q)
 		}
-		softmax(values)
+		Softmax(values)
 
 		for j := 0; j < V.Rows; j++ {
 			V := V.Data[j*V.Cols : (j+1)*V.Cols]
-			output[j] += dot(values, V)
+			output[j] += Dot(values, V)
+		}
+	}
+	aa := sqrt(Dot(output[:], output[:]))
+	for i, v := range output {
+		output[i] = v / aa
+	}
+	return output
+}
+
+// Attention computes the self attention weights of the rows of a matrix from its gram matrix,
+// the self attention of the matrix is the sum of its rows scaled by the weights
+func Attention[T Float](gram []T, rows int) []T {
+	weights, values := make([]T, rows), make([]T, rows)
+	for i := 0; i < rows; i++ {
+		copy(values, gram[i*rows:(i+1)*rows])
+		Softmax(values)
+		for j, v := range values {
+			weights[j] += v
 		}
 	}
-	aa := math.Sqrt(dot(output[:], output[:]))
+	return weights
+}
+
+// Normalize normalizes a vector to unit length
+func Normalize[T Float](output []T) []T {
+	aa := sqrt(Dot(output, output))
 	for i, v := range output {
 		output[i] = v / aa
 	}
 	return output
 }
 
-// CS is float64 normalized cosine similarity
-func CS(a []float64, b []float64) float64 {
-	aa, bb, ab := dot(a, a), dot(b, b), dot(a, b)
+// CS is normalized cosine similarity
+func CS[T Float](a []T, b []T) T {
+	aa, bb, ab := Dot(a, a), Dot(b, b), Dot(a, b)
 	if aa <= 0 {
 		return 0
 	}
 	if bb <= 0 {
 		return 0
 	}
-	return ab / (math.Sqrt(aa) * math.Sqrt(bb))
+	return ab / (sqrt(aa) * sqrt(bb))
 }
@@ -0,0 +1,677 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mat
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// Size is the default number of histograms
+	Size = 8
+	// Order is the default order of the markov model
+	Order = 7
+)
+
+const (
+	// CDF16Fixed is the shift for 16 bit coders
+	CDF16Fixed = 16 - 3
+	// CDF16Scale is the scale for 16 bit coder
+	CDF16Scale = 1 << CDF16Fixed
+	// CDF16Rate is the damping factor for 16 bit coder
+	CDF16Rate = 5
+)
+
+// Mix is a mixer
+type Mix[T Float] interface {
+	Copy() Mix[T]
+	Add(byte)
+	Mix() []T
+}
+
+// CrossMix is a mixer of two symbol streams
+type CrossMix[T Float] interface {
+	Copy() CrossMix[T]
+	Add(byte, byte)
+	Mix() [InputSize]T
+}
+
+// Attender is a learned attention over the rows of a mixer
+type Attender[T Float] interface {
+	Attend(input Matrix[T]) []T
+}
+
+type CDF16 struct {
+	Size   int
+	Rate   int
+	Model  []uint16
+	Mixin  [][]uint16
+	Verify bool
+}
+
+type Filtered16 interface {
+	GetModel() []uint16
+	Copy() Filtered16
+	Update(s uint16)
+}
+
+type CDF16Maker func(size, rate int) Filtered16
+
+func NewCDF16(verify bool) CDF16Maker {
+	return func(size, rate int) Filtered16 {
+		if size != 256 {
+			panic("size is not 256")
+		}
+		model, sum := make([]uint16, size+1), 0
+		for i := range model {
+			model[i] = uint16(sum)
+			sum += 32
+		}
+
+		mixin := make([][]uint16, size)
+
+		for i := range mixin {
+			sum, m := 0, make([]uint16, size+1)
+			for j := range m {
+				m[j] = uint16(sum)
+				sum++
+				if j == i {
+					sum += CDF16Scale - size
+				}
+			}
+			mixin[i] = m
+		}
+
+		return &CDF16{
+			Size:   size,
+			Rate:   rate,
+			Model:  model,
+			Mixin:  mixin,
+			Verify: verify,
+		}
+	}
+}
+
+// Copy copies the model
+func (c *CDF16) Copy() Filtered16 {
+	model := make([]uint16, len(c.Model))
+	copy(model, c.Model)
+	return &CDF16{
+		Size:   c.Size,
+		Rate:   c.Rate,
+		Model:  model,
+		Mixin:  c.Mixin,
+		Verify: c.Verify,
+	}
+}
+
+// GetModel gets the cdf
+func (c *CDF16) GetModel() []uint16 {
+	return c.Model
+}
+
+// Update the cdf
+func (c *CDF16) Update(s uint16) {
+	model, mixin := c.Model, c.Mixin[s]
+	size, rate := len(model)-1, c.Rate
+
+	if c.Verify {
+		for i := 1; i < size; i++ {
+			a, b := int(model[i]), int(mixin[i])
+			if a < 0 {
+				panic("a is less than zero")
+			}
+			if b < 0 {
+				panic("b is less than zero")
+			}
+			model[i] = uint16(a + ((b - a) >> rate))
+		}
+		if model[size] != CDF16Scale {
+			panic("cdf scale is incorrect")
+		}
+		for i := 1; i < len(model); i++ {
+			if a, b := model[i], model[i-1]; a < b {
+				panic(fmt.Sprintf("invalid cdf %v,%v < %v,%v", i, a, i-1, b))
+			} else if a == b {
+				panic(fmt.Sprintf("invalid cdf %v,%v = %v,%v", i, a, i-1, b))
+			}
+		}
+	} else {
+		for i := 1; i < size; i++ {
+			a, b := int(model[i]), int(mixin[i])
+			model[i] = uint16(a + ((b - a) >> rate))
+		}
+	}
+}
+
+// Markov is a markov model
+type Markov []byte
+
+// NewMarkov makes a new markov model
+func NewMarkov(order int) Markov {
+	return make(Markov, order+1)
+}
+
+// Copy copies the markov model
+func (m Markov) Copy() Markov {
+	cp := make(Markov, len(m))
+	copy(cp, m)
+	return cp
+}
+
+// Add adds a symbol to the markov model
+func (m Markov) Add(s byte) {
+	for k := len(m) - 1; k > 0; k-- {
+		m[k] = m[k-1]
+	}
+	m[0] = s
+}
+
+// Histogram is a buffered histogram
+type Histogram struct {
+	Vector []int
+	Buffer []byte
+	Index  int
+	Size   int
+}
+
+// NewHistogram make a new histogram
+func NewHistogram(size, length int) Histogram {
+	h := Histogram{
+		Vector: make([]int, length),
+		Buffer: make([]byte, size),
+		Size:   size,
+	}
+	return h
+}
+
+// Copy copies the histogram
+func (h Histogram) Copy() Histogram {
+	vector, buffer := make([]int, len(h.Vector)), make([]byte, len(h.Buffer))
+	copy(vector, h.Vector)
+	copy(buffer, h.Buffer)
+	h.Vector, h.Buffer = vector, buffer
+	return h
+}
+
+// Add adds a symbol to the histogram returning the symbol that was removed if any
+func (h *Histogram) Add(s byte) (removed byte, ok bool) {
+	index := (h.Index + 1) % h.Size
+	if symbol := h.Buffer[index]; h.Vector[symbol] > 0 {
+		h.Vector[symbol]--
+		removed, ok = symbol, true
+	}
+	h.Buffer[index] = s
+	h.Vector[s]++
+	h.Index = index
+	return removed, ok
+}
+
+// Fill adds a symbol to a histogram that holds filled symbols, nothing is removed until the buffer is full
+func (h *Histogram) Fill(s byte, filled int) (removed byte, ok bool) {
+	if filled >= h.Size {
+		return h.Add(s)
+	}
+	h.Index = (h.Index + 1) % h.Size
+	h.Buffer[h.Index] = s
+	h.Vector[s]++
+	return 0, false
+}
+
+// Gram is an incrementally maintained gram matrix of the histograms
+type Gram struct {
+	Rows   int
+	Counts []int64
+	Sums   []int64
+}
+
+// NewGram makes a new gram matrix for rows histograms
+func NewGram(rows int) Gram {
+	return Gram{
+		Rows:   rows,
+		Counts: make([]int64, rows*rows),
+		Sums:   make([]int64, rows),
+	}
+}
+
+// Copy copies the gram matrix
+func (g Gram) Copy() Gram {
+	counts, sums := make([]int64, len(g.Counts)), make([]int64, len(g.Sums))
+	copy(counts, g.Counts)
+	copy(sums, g.Sums)
+	g.Counts, g.Sums = counts, sums
+	return g
+}
+
+// change updates the gram matrix after entry k of histogram i changed by delta
+func (g *Gram) change(histograms []Histogram, i int, k byte, delta int64) {
+	rows := g.Rows
+	for j := range histograms {
+		if j == i {
+			continue
+		}
+		d := delta * int64(histograms[j].Vector[k])
+		g.Counts[i*rows+j] += d
+		g.Counts[j*rows+i] += d
+	}
+	g.Counts[i*rows+i] += 2*delta*int64(histograms[i].Vector[k]) - delta*delta
+	g.Sums[i] += delta
+}
+
+// Update updates the gram matrix after histogram i added s and removed removed if ok
+func (g *Gram) Update(histograms []Histogram, i int, s, removed byte, ok bool) {
+	if ok && removed == s {
+		return
+	}
+	g.change(histograms, i, s, 1)
+	if ok {
+		g.change(histograms, i, removed, -1)
+	}
+}
+
+// MixGram computes the self attention of the normalized histograms from their gram matrix
+func MixGram[T Float](g *Gram, histograms []Histogram, length int) []T {
+	rows := g.Rows
+	gram := make([]T, rows*rows)
+	for i := range rows {
+		for j := range rows {
+			if g.Sums[i] == 0 || g.Sums[j] == 0 {
+				continue
+			}
+			gram[i*rows+j] = T(float64(g.Counts[i*rows+j]) / float64(g.Sums[i]*g.Sums[j]))
+		}
+	}
+	weights := Attention(gram, rows)
+	output := make([]T, length)
+	for i := range histograms {
+		if g.Sums[i] == 0 {
+			continue
+		}
+		w := weights[i] / T(g.Sums[i])
+		for k, v := range histograms[i].Vector {
+			output[k] += w * T(v)
+		}
+	}
+	return Normalize(output)
+}
+
+// Filtered is a filtered counter
+type Filtered[T Float] struct {
+	Markov  Markov
+	Filters []Filtered16
+	// Head is an optional learned attention head
+	Head Attender[T]
+}
+
+// NewFiltered makes a new filtered counter
+func NewFiltered[T Float]() *Filtered[T] {
+	return NewFilteredWithConfig[T](DefaultMixerConfig())
+}
+
+// NewFilteredWithConfig makes a new filtered counter with a configuration
+func NewFilteredWithConfig[T Float](config MixerConfig) *Filtered[T] {
+	cdf := NewCDF16(false)
+	filters := make([]Filtered16, len(config.Rates))
+	for i := range filters {
+		filters[i] = cdf(256, config.Rates[i])
+	}
+	return &Filtered[T]{
+		Markov:  NewMarkov(config.Order),
+		Filters: filters,
+	}
+}
+
+// Copy copies the filter
+func (f Filtered[T]) Copy() Mix[T] {
+	filters := make([]Filtered16, len(f.Filters))
+	for i := range filters {
+		filters[i] = f.Filters[i].Copy()
+	}
+	return &Filtered[T]{
+		Markov:  f.Markov.Copy(),
+		Filters: filters,
+		Head:    f.Head,
+	}
+}
+
+// Add adds a symbol to a filter
+func (f *Filtered[T]) Add(s byte) {
+	for i := range f.Filters {
+		f.Filters[i].Update(uint16(s))
+	}
+	f.Markov.Add(s)
+}
+
+// Rows are the rows of the filtered counter that are mixed
+func (f Filtered[T]) Rows() Matrix[T] {
+	x := NewMatrix[T](256, len(f.Filters)+len(f.Markov))
+	for i := range f.Filters {
+		model := f.Filters[i].GetModel()
+		last, sum := uint16(0), T(0.0)
+		for _, v := range model[1:] {
+			sum += T(v - last)
+			last = v
+		}
+		last = 0
+		for _, v := range model[1:] {
+			x.Data = append(x.Data, T(v-last)/sum)
+			last = v
+		}
+	}
+	for _, v := range f.Markov {
+		d := make([]T, 256)
+		d[v] = 1
+		x.Data = append(x.Data, d...)
+	}
+	return x
+}
+
+// Mix mixes the filters outputting a matrix, the markov rows are one hot
+// so their dot products are looked up instead of computed
+func (f Filtered[T]) Mix() []T {
+	if f.Head != nil {
+		return f.Head.Attend(f.Rows())
+	}
+	filters := len(f.Filters)
+	rows := filters + len(f.Markov)
+	pdfs := make([][]T, filters)
+	for i := range f.Filters {
+		model := f.Filters[i].GetModel()
+		last, sum := uint16(0), T(0.0)
+		for _, v := range model[1:] {
+			sum += T(v - last)
+			last = v
+		}
+		last = 0
+		pdf := make([]T, 0, 256)
+		for _, v := range model[1:] {
+			pdf = append(pdf, T(v-last)/sum)
+			last = v
+		}
+		pdfs[i] = pdf
+	}
+	gram := make([]T, rows*rows)
+	for i := range pdfs {
+		for j := i; j < filters; j++ {
+			d := Dot(pdfs[i], pdfs[j])
+			gram[i*rows+j], gram[j*rows+i] = d, d
+		}
+		for j, v := range f.Markov {
+			d := pdfs[i][v]
+			gram[i*rows+filters+j], gram[(filters+j)*rows+i] = d, d
+		}
+	}
+	for i, a := range f.Markov {
+		for j, b := range f.Markov {
+			if a == b {
+				gram[(filters+i)*rows+filters+j] = 1
+			}
+		}
+	}
+	weights := Attention(gram, rows)
+	output := make([]T, 256)
+	for i, pdf := range pdfs {
+		w := weights[i]
+		for k, v := range pdf {
+			output[k] += w * v
+		}
+	}
+	for j, v := range f.Markov {
+		output[v] += weights[filters+j]
+	}
+	return Normalize(output)
+}
+
+// NewHistograms makes the histograms for the windows of a configuration
+func NewHistograms(length int, config MixerConfig) []Histogram {
+	histograms := make([]Histogram, len(config.Windows))
+	for i, window := range config.Windows {
+		histograms[i] = NewHistogram(window, length)
+	}
+	return histograms
+}
+
+// CopyHistograms copies histograms
+func CopyHistograms(h []Histogram) []Histogram {
+	histograms := make([]Histogram, len(h))
+	for i := range h {
+		histograms[i] = h[i].Copy()
+	}
+	return histograms
+}
+
+// Mixer mixes several histograms together
+type Mixer[T Float] struct {
+	Markov     Markov
+	Histograms []Histogram
+	Gram       Gram
+	Length     int
+}
+
+// NewMixer makes a new mixer
+func NewMixer[T Float](length int) *Mixer[T] {
+	return NewMixerWithConfig[T](length, DefaultMixerConfig())
+}
+
+// NewMixerWithConfig makes a new mixer with a configuration
+func NewMixerWithConfig[T Float](length int, config MixerConfig) *Mixer[T] {
+	return &Mixer[T]{
+		Markov:     NewMarkov(config.Order),
+		Histograms: NewHistograms(length, config),
+		Gram:       NewGram(len(config.Windows)),
+		Length:     length,
+	}
+}
+
+func (m Mixer[T]) Copy() Mix[T] {
+	return &Mixer[T]{
+		Markov:     m.Markov.Copy(),
+		Histograms: CopyHistograms(m.Histograms),
+		Gram:       m.Gram.Copy(),
+		Length:     m.Length,
+	}
+}
+
+// Add adds a symbol to a mixer
+func (m *Mixer[T]) Add(s byte) {
+	for i := range m.Histograms {
+		removed, ok := m.Histograms[i].Add(s)
+		m.Gram.Update(m.Histograms, i, s, removed, ok)
+	}
+	m.Markov.Add(s)
+}
+
+// Mix mixes the histograms outputting a matrix
+func (m Mixer[T]) Mix() []T {
+	return MixGram[T](&m.Gram, m.Histograms, m.Length)
+}
+
+// Basic mixes several histograms together
+type Basic[T Float] struct {
+	Markov     Markov
+	Histograms []Histogram
+	Gram       Gram
+	Length     int
+}
+
+// NewBasic makes a new mixer
+func NewBasic[T Float](length int) *Basic[T] {
+	return NewBasicWithConfig[T](length, DefaultMixerConfig())
+}
+
+// NewBasicWithConfig makes a new mixer with a configuration
+func NewBasicWithConfig[T Float](length int, config MixerConfig) *Basic[T] {
+	return &Basic[T]{
+		Markov:     NewMarkov(config.Order),
+		Histograms: NewHistograms(length, config),
+		Gram:       NewGram(len(config.Windows)),
+		Length:     length,
+	}
+}
+
+func (b Basic[T]) Copy() Mix[T] {
+	return &Basic[T]{
+		Markov:     b.Markov.Copy(),
+		Histograms: CopyHistograms(b.Histograms),
+		Gram:       b.Gram.Copy(),
+		Length:     b.Length,
+	}
+}
+
+// Add adds a symbol to a mixer
+func (b *Basic[T]) Add(s byte) {
+	for i := range b.Histograms {
+		removed, ok := b.Histograms[i].Add(s)
+		b.Gram.Update(b.Histograms, i, s, removed, ok)
+	}
+	b.Markov.Add(s)
+}
+
+// Mix mixes the histograms outputting a matrix
+func (b Basic[T]) Mix() []T {
+	return MixGram[T](&b.Gram, b.Histograms, b.Length)
+}
+
+// DecayedHistogram is an exponentially decayed histogram
+type DecayedHistogram[T Float] struct {
+	Vector []T
+	Sum    T
+	Weight T
+	Decay  T
+}
+
+// NewDecayedHistogram makes a new exponentially decayed histogram
+func NewDecayedHistogram[T Float](halfLife float64, length int) DecayedHistogram[T] {
+	return DecayedHistogram[T]{
+		Vector: make([]T, length),
+		Weight: 1,
+		Decay:  T(math.Pow(2, -1/halfLife)),
+	}
+}
+
+// Copy copies the decayed histogram
+func (h DecayedHistogram[T]) Copy() DecayedHistogram[T] {
+	vector := make([]T, len(h.Vector))
+	copy(vector, h.Vector)
+	h.Vector = vector
+	return h
+}
+
+// Add adds a symbol to the decayed histogram, instead of decaying the counts
+// the weight of new symbols grows and the counts are rescaled before they overflow,
+// the rescaling factor is returned
+func (h *DecayedHistogram[T]) Add(s byte) (scale T) {
+	h.Weight /= h.Decay
+	h.Vector[s] += h.Weight
+	h.Sum += h.Weight
+	scale = 1
+	if h.Weight > 1e30 {
+		scale = 1 / h.Weight
+		for i := range h.Vector {
+			h.Vector[i] *= scale
+		}
+		h.Sum *= scale
+		h.Weight = 1
+	}
+	return scale
+}
+
+// Decayed mixes several exponentially decayed histograms together
+type Decayed[T Float] struct {
+	Markov     Markov
+	Histograms []DecayedHistogram[T]
+	Gram       []float64
+	Length     int
+}
+
+// NewDecayed makes a new decayed mixer
+func NewDecayed[T Float](length int) *Decayed[T] {
+	return NewDecayedWithConfig[T](length, DefaultMixerConfig())
+}
+
+// NewDecayedWithConfig makes a new decayed mixer with a configuration
+func NewDecayedWithConfig[T Float](length int, config MixerConfig) *Decayed[T] {
+	histograms := make([]DecayedHistogram[T], len(config.HalfLives))
+	for i, halfLife := range config.HalfLives {
+		histograms[i] = NewDecayedHistogram[T](halfLife, length)
+	}
+	return &Decayed[T]{
+		Markov:     NewMarkov(config.Order),
+		Histograms: histograms,
+		Gram:       make([]float64, len(histograms)*len(histograms)),
+		Length:     length,
+	}
+}
+
+// Copy copies the decayed mixer
+func (d Decayed[T]) Copy() Mix[T] {
+	histograms := make([]DecayedHistogram[T], len(d.Histograms))
+	for i := range d.Histograms {
+		histograms[i] = d.Histograms[i].Copy()
+	}
+	gram := make([]float64, len(d.Gram))
+	copy(gram, d.Gram)
+	return &Decayed[T]{
+		Markov:     d.Markov.Copy(),
+		Histograms: histograms,
+		Gram:       gram,
+		Length:     d.Length,
+	}
+}
+
+// Add adds a symbol to the decayed mixer, the gram matrix of the unnormalized
+// histograms is updated before each histogram changes
+func (d *Decayed[T]) Add(s byte) {
+	rows := len(d.Histograms)
+	for i := range d.Histograms {
+		h := &d.Histograms[i]
+		w := float64(h.Weight / h.Decay)
+		for j := range d.Histograms {
+			if j == i {
+				continue
+			}
+			v := w * float64(d.Histograms[j].Vector[s])
+			d.Gram[i*rows+j] += v
+			d.Gram[j*rows+i] += v
+		}
+		d.Gram[i*rows+i] += 2*w*float64(h.Vector[s]) + w*w
+		if scale := float64(h.Add(s)); scale != 1 {
+			for j := range rows {
+				d.Gram[i*rows+j] *= scale
+				d.Gram[j*rows+i] *= scale
+			}
+		}
+	}
+	d.Markov.Add(s)
+}
+
+// Mix mixes the decayed histograms outputting a matrix
+func (d Decayed[T]) Mix() []T {
+	rows := len(d.Histograms)
+	gram := make([]T, rows*rows)
+	for i := range rows {
+		for j := range rows {
+			a, b := d.Histograms[i].Sum, d.Histograms[j].Sum
+			if a == 0 || b == 0 {
+				continue
+			}
+			gram[i*rows+j] = T(d.Gram[i*rows+j] / (float64(a) * float64(b)))
+		}
+	}
+	weights := Attention(gram, rows)
+	output := make([]T, d.Length)
+	for i := range d.Histograms {
+		sum := d.Histograms[i].Sum
+		if sum == 0 {
+			continue
+		}
+		w := weights[i] / sum
+		for k, v := range d.Histograms[i].Vector {
+			output[k] += w * v
+		}
+	}
+	return Normalize(output)
+}
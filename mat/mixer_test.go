@@ -0,0 +1,261 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mat
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestCDF(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 1; i < 9; i++ {
+		cdf := NewCDF16(true)
+		filtered := cdf(256, i)
+		for j := 0; j < 1024; j++ {
+			filtered.Update(uint16(rng.Intn(256)))
+			t.Log(filtered.GetModel())
+		}
+	}
+}
+
+func TestCDFCopy(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 1; i < 9; i++ {
+		cdf := NewCDF16(true)
+		filtered := cdf(256, i)
+		cp := filtered.Copy()
+		for j := 0; j < 1024; j++ {
+			x := rng.Intn(256)
+			filtered.Update(uint16(x))
+			cp.Update(uint16(x))
+			t.Log(filtered.GetModel())
+		}
+		a, b := filtered.GetModel(), cp.GetModel()
+		for i, v := range a {
+			if v != b[i] {
+				t.Fatalf("%d != %d", v, b[i])
+			}
+		}
+	}
+}
+
+func TestFiltered(t *testing.T) {
+	a := NewFiltered[float32]()
+	a.Add(1)
+	a.Add(1)
+	b := NewFiltered[float32]()
+	b.Add(1)
+	c := NewFiltered[float32]()
+	c.Add(1)
+	x := a.Mix()
+	y := b.Mix()
+	z := c.Mix()
+	i := CS(z[:], x[:])
+	j := CS(z[:], y[:])
+	if j < i {
+		t.Fatalf("%f < %f", j, i)
+	}
+}
+
+func TestHistogramFill(t *testing.T) {
+	h := NewHistogram(2, 4)
+	for filled, s := range []byte{0, 0, 1} {
+		removed, ok := h.Fill(s, filled)
+		if ok != (filled == 2) || removed != 0 {
+			t.Fatalf("%d removed %d %t", filled, removed, ok)
+		}
+	}
+	if h.Vector[0] != 1 || h.Vector[1] != 1 {
+		t.Fatalf("%v", h.Vector)
+	}
+}
+
+func TestDecayed(t *testing.T) {
+	d := NewDecayed[float32](256)
+	for i := range 10000 {
+		d.Add(byte(i % 3))
+	}
+	d.Add(9)
+	h := d.Histograms[0]
+	if p := h.Vector[9] / h.Sum; p < .49 || p > .51 {
+		t.Fatalf("%f != .5", p)
+	}
+	cp := d.Copy()
+	d.Add(4)
+	cp.Add(4)
+	x, y := d.Mix(), cp.Mix()
+	for i, v := range x {
+		if math.IsNaN(float64(v)) || v != y[i] {
+			t.Fatalf("%d: %f != %f", i, v, y[i])
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	mix := func(text string) Mix[float32] {
+		w := NewWords[float32](Classes(nil))
+		for _, v := range []byte(text) {
+			w.Add(v)
+		}
+		return w
+	}
+	a, b := mix("of the king"), mix("of thinking")
+	if CS(a.Mix(), b.Mix()) > .9 {
+		t.Fatal("the word contexts are not distinguished")
+	}
+	cp := a.Copy()
+	a.Add('s')
+	if CS(a.Mix(), cp.Mix()) > .9 {
+		t.Fatal("the copy was modified")
+	}
+	cp.Add('s')
+	near(t, "words", 0, a.Mix(), cp.Mix())
+	w := mix("Hello, world.\n").(*Words[float32])
+	if !w.LineStart || w.Position != 0 || w.Pending != '.' {
+		t.Fatalf("%v %d %c", w.LineStart, w.Position, w.Pending)
+	}
+	w = mix("Hello, world").(*Words[float32])
+	if w.LineStart || w.Position != 5 || w.Punctuation != ',' {
+		t.Fatalf("%v %d %c", w.LineStart, w.Position, w.Punctuation)
+	}
+}
+
+func TestIncremental(t *testing.T) {
+	incremental[float32](t)
+	incremental[float64](t)
+}
+
+func incremental[T Float](t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	config := MixerConfig{
+		Windows:   []int{1, 3, 8, 300},
+		Order:     4,
+		Rates:     []int{1, 3, 5},
+		HalfLives: []float64{1, 10, 1000},
+	}
+	mixers := []Mix[T]{
+		NewMixerWithConfig[T](256, config),
+		NewBasicWithConfig[T](256, config),
+		NewDecayedWithConfig[T](256, config),
+		NewFilteredWithConfig[T](config),
+	}
+	for i := 0; i < 2048; i++ {
+		s := byte(rng.Intn(8))
+		if rng.Intn(4) == 0 {
+			s = byte(rng.Intn(256))
+		}
+		for j := range mixers {
+			mixers[j].Add(s)
+		}
+		if i%64 == 0 {
+			for j := range mixers {
+				mixers[j] = mixers[j].Copy()
+			}
+		}
+		for _, m := range mixers {
+			rows := [][]T{}
+			switch m := m.(type) {
+			case *Mixer[T]:
+				for _, h := range m.Histograms {
+					rows = append(rows, normalized[T](h.Vector))
+				}
+				near(t, "mixer", i, m.Mix(), reference(rows))
+			case *Basic[T]:
+				for _, h := range m.Histograms {
+					rows = append(rows, normalized[T](h.Vector))
+				}
+				near(t, "basic", i, m.Mix(), reference(rows))
+			case *Decayed[T]:
+				for _, h := range m.Histograms {
+					row := make([]T, len(h.Vector))
+					for k, v := range h.Vector {
+						row[k] = v / h.Sum
+					}
+					rows = append(rows, row)
+				}
+				near(t, "decayed", i, m.Mix(), reference(rows))
+			case *Filtered[T]:
+				for _, filter := range m.Filters {
+					model := filter.GetModel()
+					row := make([]T, 256)
+					for k := range row {
+						row[k] = T(model[k+1]-model[k]) / CDF16Scale
+					}
+					rows = append(rows, row)
+				}
+				for _, v := range m.Markov {
+					row := make([]T, 256)
+					row[v] = 1
+					rows = append(rows, row)
+				}
+				near(t, "filtered", i, m.Mix(), reference(rows))
+			}
+		}
+	}
+}
+
+// reference computes the self attention of the rows from scratch
+func reference[T Float](rows [][]T) []T {
+	x := NewMatrix[T](len(rows[0]), len(rows))
+	for _, row := range rows {
+		x.Data = append(x.Data, row...)
+	}
+	return SelfAttention(x)
+}
+
+func normalized[T Float](vector []int) []T {
+	sum, row := T(0.0), make([]T, len(vector))
+	for _, v := range vector {
+		sum += T(v)
+	}
+	if sum == 0 {
+		return row
+	}
+	for i, v := range vector {
+		row[i] = T(v) / sum
+	}
+	return row
+}
+
+func near[T Float](t *testing.T, name string, step int, a, b []T) {
+	t.Helper()
+	for i, v := range a {
+		if diff := math.Abs(float64(v - b[i])); diff > 1e-4 {
+			t.Fatalf("%s %d %d: %f != %f", name, step, i, v, b[i])
+		}
+	}
+}
+
+func TestDotAllocs(t *testing.T) {
+	a, b := make([]float32, InputSize), make([]float32, InputSize)
+	if allocs := testing.AllocsPerRun(100, func() { Dot(a, b) }); allocs != 0 {
+		t.Fatalf("%f allocations", allocs)
+	}
+}
+
+func BenchmarkFilteredMix(b *testing.B) {
+	m := NewFiltered[float32]()
+	for _, s := range []byte("the quick brown fox") {
+		m.Add(s)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Mix()
+	}
+}
+
+func BenchmarkMixerMix(b *testing.B) {
+	m := NewMixer[float32](256)
+	for _, s := range []byte("the quick brown fox jumps over the lazy dog") {
+		m.Add(s)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Add(byte(i))
+		m.Mix()
+	}
+}
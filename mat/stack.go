@@ -0,0 +1,66 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mat
+
+import (
+	"fmt"
+)
+
+// Stack is a stack of mixers, the outputs are concatenated or fused with self attention
+type Stack[T Float] struct {
+	Markov Markov
+	Mixers []Mix[T]
+	// Concat concatenates the outputs instead of fusing them
+	Concat bool
+}
+
+// NewStack makes a new stack of mixers with a markov model of order
+func NewStack[T Float](order int, concat bool, mixers ...Mix[T]) *Stack[T] {
+	return &Stack[T]{
+		Markov: NewMarkov(order),
+		Mixers: mixers,
+		Concat: concat,
+	}
+}
+
+// Copy copies the stack of mixers
+func (s Stack[T]) Copy() Mix[T] {
+	mixers := make([]Mix[T], len(s.Mixers))
+	for i := range mixers {
+		mixers[i] = s.Mixers[i].Copy()
+	}
+	return &Stack[T]{
+		Markov: s.Markov.Copy(),
+		Mixers: mixers,
+		Concat: s.Concat,
+	}
+}
+
+// Add adds a symbol to each of the mixers
+func (s *Stack[T]) Add(b byte) {
+	for _, m := range s.Mixers {
+		m.Add(b)
+	}
+	s.Markov.Add(b)
+}
+
+// Mix concatenates or fuses the outputs of the mixers, fused outputs must be the same size
+func (s Stack[T]) Mix() []T {
+	var outputs []T
+	cols := 0
+	for i, m := range s.Mixers {
+		output := m.Mix()
+		if i == 0 {
+			cols = len(output)
+		} else if !s.Concat && len(output) != cols {
+			panic(fmt.Errorf("%d != %d", len(output), cols))
+		}
+		outputs = append(outputs, output...)
+	}
+	if s.Concat {
+		return Normalize(outputs)
+	}
+	return SelfAttention(NewMatrix(cols, len(s.Mixers), outputs...))
+}
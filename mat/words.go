@@ -0,0 +1,127 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mat
+
+const (
+	// WordsOrder is the number of hashed words including the current partial word
+	WordsOrder = 3
+	// WordsOffset is the offset basis of the word hash
+	WordsOffset = 2166136261
+	// WordsPrime is the prime of the word hash
+	WordsPrime = 16777619
+)
+
+// Classes are the character classes of the symbols of an alphabet,
+// symbols are treated as runes if reverse is nil
+func Classes(reverse map[byte]rune) []byte {
+	classes := make([]byte, 256)
+	for i := range classes {
+		r := rune(i)
+		if reverse != nil {
+			r = reverse[byte(i)]
+		}
+		classes[i] = Class(r)
+	}
+	return classes
+}
+
+// Words is a mixer of word level context features: the hashed previous words,
+// the position within the word, the line start state and the punctuation before the word
+type Words[T Float] struct {
+	Classes []byte
+	// Words are the hashes of the previous words, Words[0] is the current partial word
+	Words []uint32
+	// Position is the position within the current word
+	Position int
+	// LineStart is true if only spaces have been seen since the start of the line
+	LineStart bool
+	// Punctuation is the punctuation between the previous word and the current word
+	Punctuation byte
+	// Pending is the punctuation seen since the last word
+	Pending byte
+}
+
+// NewWords makes a new word feature mixer for the character classes of the symbols
+func NewWords[T Float](classes []byte) *Words[T] {
+	words := make([]uint32, WordsOrder)
+	for i := range words {
+		words[i] = WordsOffset
+	}
+	return &Words[T]{
+		Classes:   classes,
+		Words:     words,
+		LineStart: true,
+	}
+}
+
+// Copy copies the word feature mixer
+func (w Words[T]) Copy() Mix[T] {
+	words := make([]uint32, len(w.Words))
+	copy(words, w.Words)
+	w.Words = words
+	return &w
+}
+
+// Add adds a symbol to the word feature mixer
+func (w *Words[T]) Add(s byte) {
+	switch class := w.Classes[s]; class {
+	case 1, 2, 3:
+		if w.Position == 0 {
+			w.Punctuation, w.Pending = w.Pending, 0
+		}
+		w.Words[0] = (w.Words[0] ^ uint32(s)) * WordsPrime
+		w.Position++
+		w.LineStart = false
+		return
+	case 4:
+		w.LineStart = true
+	case 6:
+		w.Pending = s
+		w.LineStart = false
+	case 5:
+	default:
+		w.LineStart = false
+	}
+	if w.Position > 0 {
+		copy(w.Words[1:], w.Words)
+		w.Words[0] = WordsOffset
+		w.Position = 0
+	}
+}
+
+// Rows are the one hot feature rows, the index of each row is hashed with the feature number
+func (w Words[T]) Rows() Matrix[T] {
+	features := make([]uint32, 0, len(w.Words)+3)
+	features = append(features, w.Words...)
+	position := uint32(w.Position)
+	if position > 15 {
+		position = 15
+	}
+	lineStart := uint32(0)
+	if w.LineStart {
+		lineStart = 1
+	}
+	features = append(features, position, lineStart, uint32(w.Punctuation))
+	x := NewMatrix[T](256, len(features))
+	for i, feature := range features {
+		h := ((WordsOffset^uint32(i))*WordsPrime ^ feature) * WordsPrime
+		d := make([]T, 256)
+		d[(h^h>>16)&0xFF] = 1
+		x.Data = append(x.Data, d...)
+	}
+	return x
+}
+
+// Mix sums the feature rows
+func (w Words[T]) Mix() []T {
+	rows := w.Rows()
+	output := make([]T, rows.Cols)
+	for i := 0; i < rows.Rows; i++ {
+		for j, v := range rows.Data[i*rows.Cols : (i+1)*rows.Cols] {
+			output[j] += v
+		}
+	}
+	return Normalize(output)
+}
@@ -0,0 +1,187 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"sort"
+
+	"github.com/alixaxel/pagerank"
+)
+
+// Expansion is a symbol that a node of the tree search can be expanded with
+type Expansion struct {
+	Symbol byte
+	// Value is the pagerank of the symbol
+	Value float64
+}
+
+// PageRank expands with the symbols of the neighbors, the value of a symbol is the sum of the
+// pageranks of its neighbors in the similarity graph of the neighbors
+func PageRank(items []Item, neighbors []Neighbor) []Expansion {
+	graph := pagerank.NewGraph()
+	for i := range neighbors {
+		for j := range neighbors {
+			p := dot(&items[neighbors[i].Index].Vector, items[neighbors[j].Index].Vector[:])
+			graph.Link(uint32(i), uint32(j), p)
+		}
+	}
+	values := make(map[byte]float64)
+	graph.Rank(1.0, 1e-3, func(node uint32, rank float64) {
+		values[neighbors[node].Symbol] += rank
+	})
+	expansions := make([]Expansion, 0, len(values))
+	for symbol, value := range values {
+		expansions = append(expansions, Expansion{symbol, value})
+	}
+	sort.Slice(expansions, func(i, j int) bool {
+		if expansions[i].Value == expansions[j].Value {
+			return expansions[i].Symbol < expansions[j].Symbol
+		}
+		return expansions[i].Value > expansions[j].Value
+	})
+	return expansions
+}
+
+// Node is a node of the tree search, the mixer and the text of a node are copied from its parent when it is first visited
+type Node struct {
+	Mix  Mix
+	Text *Text
+	// Done is true if the symbol of the node finishes the text
+	Done     bool
+	Symbol   byte
+	Prior    float64
+	Visits   int
+	Value    float64
+	Children []*Node
+	Expanded bool
+}
+
+// Q is the mean value of the node
+func (n *Node) Q() float64 {
+	if n.Visits == 0 {
+		return 0
+	}
+	return n.Value / float64(n.Visits)
+}
+
+// Best is the most visited child of the node
+func (n *Node) Best() *Node {
+	var best *Node
+	for _, child := range n.Children {
+		if best == nil || child.Visits > best.Visits {
+			best = child
+		}
+	}
+	return best
+}
+
+// MCTS is a monte carlo tree search decoder
+type MCTS struct {
+	// Iterations is the number of simulations for each symbol
+	Iterations int
+	// Depth is the maximum depth of a simulation
+	Depth int
+	// Length is the number of symbols decoded
+	Length int
+	// Exploration weights the prior of a node against its value
+	Exploration float64
+	// Expand returns the expansions of a mixer
+	Expand func(m Mix) []Expansion
+}
+
+// visit makes the mixer and the text of a child node
+func (t MCTS) visit(parent, child *Node) {
+	if child.Text != nil {
+		return
+	}
+	child.Text = parent.Text.Copy()
+	if child.Text.Add(child.Symbol) {
+		child.Done = true
+		return
+	}
+	child.Mix = parent.Mix.Copy()
+	child.Mix.Add(child.Symbol)
+}
+
+// expand adds the children of a node, the penalty and the constraint of the text of the node are applied to the expansions
+func (t MCTS) expand(node *Node) {
+	node.Expanded = true
+	expansions := t.Expand(node.Mix)
+	if node.Text.Penalty != nil || node.Text.Constraint != nil {
+		distribution := make([]float32, 256)
+		for _, expansion := range expansions {
+			distribution[expansion.Symbol] = float32(expansion.Value)
+		}
+		distribution = node.Text.Apply(distribution)
+		filtered := expansions[:0]
+		for _, expansion := range expansions {
+			if value := distribution[expansion.Symbol]; value > 0 {
+				filtered = append(filtered, Expansion{expansion.Symbol, float64(value)})
+			}
+		}
+		expansions = filtered
+	}
+	for _, expansion := range expansions {
+		node.Children = append(node.Children, &Node{Symbol: expansion.Symbol, Prior: expansion.Value})
+	}
+}
+
+// Simulate runs a simulation from the root, the value of the leaf is its pagerank
+// and it is backed up along the path, a simulation ends at a node that finishes the text
+func (t MCTS) Simulate(root *Node) {
+	path, node := []*Node{root}, root
+	for depth := 0; depth < t.Depth; depth++ {
+		if node.Done {
+			break
+		}
+		if !node.Expanded {
+			t.expand(node)
+			if node != root {
+				break
+			}
+		}
+		if len(node.Children) == 0 {
+			break
+		}
+		var selected *Node
+		max, n := math.Inf(-1), math.Sqrt(float64(node.Visits+1))
+		for _, child := range node.Children {
+			score := child.Q() + t.Exploration*child.Prior*n/float64(1+child.Visits)
+			if score > max {
+				max, selected = score, child
+			}
+		}
+		t.visit(node, selected)
+		node = selected
+		path = append(path, node)
+	}
+	value := node.Prior
+	for _, node := range path {
+		node.Visits++
+		node.Value += value
+	}
+}
+
+// Search decodes the most visited path from m into a copy of text until the text is finished,
+// the tree below each decoded symbol is reused
+func (t MCTS) Search(m Mix, text *Text) *Text {
+	root := &Node{Mix: m.Copy(), Text: text.Copy()}
+	for range t.Length {
+		for range t.Iterations {
+			t.Simulate(root)
+		}
+		best := root.Best()
+		if best == nil {
+			break
+		}
+		t.visit(root, best)
+		root = best
+		if root.Done {
+			break
+		}
+	}
+	return root.Text
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMCTS(t *testing.T) {
+	tree := MCTS{
+		Iterations:  64,
+		Depth:       4,
+		Length:      3,
+		Exploration: 1,
+		Expand: func(m Mix) []Expansion {
+			var expansions []Expansion
+			for symbol, p := range m.Mix() {
+				if p > 0 {
+					expansions = append(expansions, Expansion{byte(symbol), float64(p)})
+				}
+			}
+			return expansions
+		},
+	}
+	text := tree.Search(&chain{Last: 2}, NewText(Stop{}, nil))
+	if len(text.Symbols) != 3 || text.Finish != FinishLength {
+		t.Fatalf("%d != 3", len(text.Symbols))
+	}
+	for _, s := range text.Symbols {
+		if s != 1 {
+			t.Fatalf("%v is not the most valuable path", text.Symbols)
+		}
+	}
+
+	stopped := tree.Search(&chain{Last: 2}, NewText(Stop{Strings: []string{"\x01\x01"}}, nil))
+	if stopped.String() != "" || stopped.Finish != FinishStop {
+		t.Fatalf("%q %v", stopped.String(), stopped.Finish)
+	}
+
+	dfa, err := NewDFA("\\x00+", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	constrained := NewText(Stop{}, nil)
+	constrained.Constraint = NewConstraint(dfa)
+	text = tree.Search(&chain{Last: 2}, constrained)
+	if string(text.Symbols) != "\x00\x00\x00" {
+		t.Fatalf("%v does not match the constraint", text.Symbols)
+	}
+	if len(constrained.Symbols) != 0 {
+		t.Fatal("the text of the search was changed")
+	}
+
+	items := make([]Item, 4)
+	for i := range items {
+		items[i].Vector[i%2] = 1
+		items[i].Symbol = byte(i % 2)
+	}
+	items[3].Symbol = 2
+	neighbors := []Neighbor{{1, 0, 0}, {1, 1, 1}, {1, 0, 2}, {1, 2, 3}}
+	expansions := PageRank(items, neighbors)
+	sum := 0.0
+	for _, expansion := range expansions {
+		sum += expansion.Value
+	}
+	if len(expansions) != 3 || math.Abs(sum-1) > 1e-3 {
+		t.Fatalf("%v", expansions)
+	}
+	if expansions[0].Symbol != 0 {
+		t.Fatalf("%d is not the highest ranked symbol", expansions[0].Symbol)
+	}
+}
@@ -5,368 +5,141 @@
 package main
 
 import (
-	"fmt"
-)
-
-const (
-	// Size is the number of histograms
-	Size = 8
-	// Order is the order of the markov model
-	Order = 7
+	"github.com/pointlander/textus/mat"
 )
 
 const (
+	// InputSize is the size of the input
+	InputSize = mat.InputSize
+	// Size is the default number of histograms
+	Size = mat.Size
+	// Order is the default order of the markov model
+	Order = mat.Order
 	// CDF16Fixed is the shift for 16 bit coders
-	CDF16Fixed = 16 - 3
+	CDF16Fixed = mat.CDF16Fixed
 	// CDF16Scale is the scale for 16 bit coder
-	CDF16Scale = 1 << CDF16Fixed
-	// CDF16Rate is the damping factor for 16 bit coder
-	CDF16Rate = 5
+	CDF16Scale = mat.CDF16Scale
 )
 
-// Mix is a mixer
-type Mix interface {
-	Copy() Mix
-	Add(byte)
-	Mix() []float32
-}
-
-// Mix is a mixer
-type CrossMix interface {
-	Copy() CrossMix
-	Add(byte, byte)
-	Mix() [InputSize]float32
-}
-
-type CDF16 struct {
-	Size   int
-	Rate   int
-	Model  []uint16
-	Mixin  [][]uint16
-	Verify bool
-}
-
-type Filtered16 interface {
-	GetModel() []uint16
-	Copy() Filtered16
-	Update(s uint16)
-}
-
-type CDF16Maker func(size, rate int) Filtered16
-
-func NewCDF16(verify bool) CDF16Maker {
-	return func(size, rate int) Filtered16 {
-		if size != 256 {
-			panic("size is not 256")
-		}
-		model, sum := make([]uint16, size+1), 0
-		for i := range model {
-			model[i] = uint16(sum)
-			sum += 32
-		}
-
-		mixin := make([][]uint16, size)
-
-		for i := range mixin {
-			sum, m := 0, make([]uint16, size+1)
-			for j := range m {
-				m[j] = uint16(sum)
-				sum++
-				if j == i {
-					sum += CDF16Scale - size
-				}
-			}
-			mixin[i] = m
-		}
-
-		return &CDF16{
-			Size:   size,
-			Rate:   rate,
-			Model:  model,
-			Mixin:  mixin,
-			Verify: verify,
-		}
-	}
-}
-
-// Copy copies the model
-func (c *CDF16) Copy() Filtered16 {
-	model := make([]uint16, len(c.Model))
-	copy(model, c.Model)
-	return &CDF16{
-		Size:   c.Size,
-		Rate:   c.Rate,
-		Model:  model,
-		Mixin:  c.Mixin,
-		Verify: c.Verify,
-	}
-}
-
-// GetModel gets the cdf
-func (c *CDF16) GetModel() []uint16 {
-	return c.Model
-}
-
-// Update the cdf
-func (c *CDF16) Update(s uint16) {
-	model, mixin := c.Model, c.Mixin[s]
-	size, rate := len(model)-1, c.Rate
-
-	if c.Verify {
-		for i := 1; i < size; i++ {
-			a, b := int(model[i]), int(mixin[i])
-			if a < 0 {
-				panic("a is less than zero")
-			}
-			if b < 0 {
-				panic("b is less than zero")
-			}
-			model[i] = uint16(a + ((b - a) >> rate))
-		}
-		if model[size] != CDF16Scale {
-			panic("cdf scale is incorrect")
-		}
-		for i := 1; i < len(model); i++ {
-			if a, b := model[i], model[i-1]; a < b {
-				panic(fmt.Sprintf("invalid cdf %v,%v < %v,%v", i, a, i-1, b))
-			} else if a == b {
-				panic(fmt.Sprintf("invalid cdf %v,%v = %v,%v", i, a, i-1, b))
-			}
-		}
-	} else {
-		for i := 1; i < size; i++ {
-			a, b := int(model[i]), int(mixin[i])
-			model[i] = uint16(a + ((b - a) >> rate))
-		}
-	}
-}
-
-// Markov is a markov model
-type Markov [Order + 1]byte
+type (
+	// Matrix is a float32 matrix
+	Matrix = mat.Matrix[float32]
+	// Mix is a float32 mixer
+	Mix = mat.Mix[float32]
+	// CrossMix is a float32 mixer of two symbol streams
+	CrossMix = mat.CrossMix[float32]
+	// MixerConfig is the configuration of a mixer
+	MixerConfig = mat.MixerConfig
+	// Filtered16 is a 16 bit cdf
+	Filtered16 = mat.Filtered16
+	// Markov is a markov model
+	Markov = mat.Markov
+	// Filtered is a float32 filtered counter
+	Filtered = mat.Filtered[float32]
+	// Mixer is a float32 histogram mixer
+	Mixer = mat.Mixer[float32]
+	// Basic is a float32 histogram mixer
+	Basic = mat.Basic[float32]
+	// Decayed is a float32 decayed histogram mixer
+	Decayed = mat.Decayed[float32]
+	// Cross is a float32 cross mixer
+	Cross = mat.Cross[float32]
+	// Words is a float32 word feature mixer
+	Words = mat.Words[float32]
+	// Stack is a float32 stack of mixers
+	Stack = mat.Stack[float32]
+)
 
-// Histogram is a buffered histogram
-type Histogram struct {
-	Vector []byte
-	Buffer [128]byte
-	Index  int
-	Size   int
+// NewMatrix creates a new float32 matrix
+func NewMatrix(cols, rows int, data ...float32) Matrix {
+	return mat.NewMatrix(cols, rows, data...)
 }
 
-// NewHistogram make a new histogram
-func NewHistogram(size, length int) Histogram {
-	h := Histogram{
-		Vector: make([]byte, length),
-		Size:   size,
-	}
-	return h
+// NewCDF16 makes a new 16 bit cdf maker
+func NewCDF16(verify bool) mat.CDF16Maker {
+	return mat.NewCDF16(verify)
 }
 
-// Add adds a symbol to the histogram
-func (h *Histogram) Add(s byte) {
-	index := (h.Index + 1) % h.Size
-	if symbol := h.Buffer[index]; h.Vector[symbol] > 0 {
-		h.Vector[symbol]--
-	}
-	h.Buffer[index] = s
-	h.Vector[s]++
-	h.Index = index
+// NewMarkov makes a new markov model
+func NewMarkov(order int) Markov {
+	return mat.NewMarkov(order)
 }
 
-// Filtered is a filtered counter
-type Filtered struct {
-	Markov  Markov
-	Filters []Filtered16
+// DefaultMixerConfig is the default mixer configuration
+func DefaultMixerConfig() MixerConfig {
+	return mat.DefaultMixerConfig()
 }
 
 // NewFiltered makes a new filtered counter
 func NewFiltered() *Filtered {
-	cdf := NewCDF16(false)
-	filters := make([]Filtered16, Size)
-	for i := range filters {
-		filters[i] = cdf(256, i+1)
-	}
-	return &Filtered{
-		Filters: filters,
-	}
+	return mat.NewFiltered[float32]()
 }
 
-// Copy copies the filter
-func (f Filtered) Copy() Mix {
-	filters := make([]Filtered16, len(f.Filters))
-	for i := range filters {
-		filters[i] = f.Filters[i].Copy()
-	}
-	return &Filtered{
-		Markov:  f.Markov,
-		Filters: filters,
-	}
+// NewFilteredWithConfig makes a new filtered counter with a configuration
+func NewFilteredWithConfig(config MixerConfig) *Filtered {
+	return mat.NewFilteredWithConfig[float32](config)
 }
 
-// Add adds a symbol to a filter
-func (f *Filtered) Add(s byte) {
-	for i := range f.Filters {
-		f.Filters[i].Update(uint16(s))
-	}
-	for k := Order; k > 0; k-- {
-		f.Markov[k] = f.Markov[k-1]
-	}
-	f.Markov[0] = s
+// NewMixer makes a new mixer
+func NewMixer(length int) *Mixer {
+	return mat.NewMixer[float32](length)
 }
 
-// Mix mixes the filters outputting a matrix
-func (f Filtered) Mix() []float32 {
-	x := NewMatrix(256, Size+Order+1)
-	for i := range f.Filters {
-		model := f.Filters[i].GetModel()
-		last, sum := uint16(0), float32(0.0)
-		for _, v := range model[1:] {
-			sum += float32(v - last)
-			last = v
-		}
-		last = 0
-		for _, v := range model[1:] {
-			x.Data = append(x.Data, float32(v-last)/sum)
-			last = v
-		}
-	}
-	for _, v := range f.Markov {
-		d := make([]float32, 256)
-		d[v] = 1
-		x.Data = append(x.Data, d...)
-	}
-	return SelfAttention(x)
+// NewMixerWithConfig makes a new mixer with a configuration
+func NewMixerWithConfig(length int, config MixerConfig) *Mixer {
+	return mat.NewMixerWithConfig[float32](length, config)
 }
 
-// Mixer mixes several histograms together
-type Mixer struct {
-	Markov     Markov
-	Histograms []Histogram
-	Length     int
+// NewBasic makes a new mixer
+func NewBasic(length int) *Basic {
+	return mat.NewBasic[float32](length)
 }
 
-// NewMixer makes a new mixer
-func NewMixer(length int) *Mixer {
-	histograms := make([]Histogram, Size)
-	histograms[0] = NewHistogram(1, length)
-	histograms[1] = NewHistogram(2, length)
-	histograms[2] = NewHistogram(4, length)
-	histograms[3] = NewHistogram(8, length)
-	histograms[4] = NewHistogram(16, length)
-	histograms[5] = NewHistogram(32, length)
-	histograms[6] = NewHistogram(64, length)
-	histograms[7] = NewHistogram(128, length)
-	return &Mixer{
-		Histograms: histograms,
-		Length:     length,
-	}
+// NewBasicWithConfig makes a new mixer with a configuration
+func NewBasicWithConfig(length int, config MixerConfig) *Basic {
+	return mat.NewBasicWithConfig[float32](length, config)
 }
 
-func (m Mixer) Copy() Mix {
-	histograms := make([]Histogram, Size)
-	for i := range m.Histograms {
-		histograms[i] = m.Histograms[i]
-		histograms[i].Vector = make([]byte, len(m.Histograms[i].Vector))
-		copy(histograms[i].Vector, m.Histograms[i].Vector)
-	}
-	return &Mixer{
-		Markov:     m.Markov,
-		Histograms: histograms,
-	}
+// NewDecayed makes a new decayed mixer
+func NewDecayed(length int) *Decayed {
+	return mat.NewDecayed[float32](length)
 }
 
-// Add adds a symbol to a mixer
-func (m *Mixer) Add(s byte) {
-	for i := range m.Histograms {
-		m.Histograms[i].Add(s)
-	}
-	for k := Order; k > 0; k-- {
-		m.Markov[k] = m.Markov[k-1]
-	}
-	m.Markov[0] = s
+// NewDecayedWithConfig makes a new decayed mixer with a configuration
+func NewDecayedWithConfig(length int, config MixerConfig) *Decayed {
+	return mat.NewDecayedWithConfig[float32](length, config)
 }
 
-// Mix mixes the histograms outputting a matrix
-func (m Mixer) Mix() []float32 {
-	x := NewMatrix(m.Length, Size)
-	for i := range m.Histograms {
-		sum := float32(0.0)
-		for _, v := range m.Histograms[i].Vector {
-			sum += float32(v)
-		}
-		for _, v := range m.Histograms[i].Vector {
-			if sum == 0.0 {
-				x.Data = append(x.Data, 0.0)
-				continue
-			}
-			x.Data = append(x.Data, float32(v)/sum)
-		}
-	}
-	return SelfAttention(x)
+// NewCrossWithConfig makes a new cross mixer with a configuration
+func NewCrossWithConfig(config MixerConfig) *Cross {
+	return mat.NewCrossWithConfig[float32](config)
 }
 
-// Basic mixes several histograms together
-type Basic struct {
-	Markov     Markov
-	Histograms []Histogram
-	Length     int
+// NewWords makes a new word feature mixer for the character classes of the symbols
+func NewWords(classes []byte) *Words {
+	return mat.NewWords[float32](classes)
 }
 
-// NewBasic makes a new mixer
-func NewBasic(length int) *Basic {
-	histograms := make([]Histogram, Size)
-	histograms[0] = NewHistogram(1, length)
-	histograms[1] = NewHistogram(2, length)
-	histograms[2] = NewHistogram(4, length)
-	histograms[3] = NewHistogram(8, length)
-	histograms[4] = NewHistogram(16, length)
-	histograms[5] = NewHistogram(32, length)
-	histograms[6] = NewHistogram(64, length)
-	histograms[7] = NewHistogram(128, length)
-	return &Basic{
-		Histograms: histograms,
-		Length:     length,
-	}
+// NewStack makes a new stack of mixers with a markov model of order
+func NewStack(order int, concat bool, mixers ...Mix) *Stack {
+	return mat.NewStack(order, concat, mixers...)
+}
+
+// SelfAttention computes the self attention of Q, K, V
+func SelfAttention(input Matrix) []float32 {
+	return mat.SelfAttention(input)
 }
 
-func (b Basic) Copy() Mix {
-	histograms := make([]Histogram, Size)
-	for i := range b.Histograms {
-		histograms[i] = b.Histograms[i]
-		histograms[i].Vector = make([]byte, len(b.Histograms[i].Vector))
-		copy(histograms[i].Vector, b.Histograms[i].Vector)
-	}
-	return &Mixer{
-		Markov:     b.Markov,
-		Histograms: histograms,
-	}
+// Normalize normalizes a vector to unit length
+func Normalize(output []float32) []float32 {
+	return mat.Normalize(output)
 }
 
-// Add adds a symbol to a mixer
-func (b *Basic) Add(s byte) {
-	for i := range b.Histograms {
-		b.Histograms[i].Add(s)
-	}
-	for k := Order; k > 0; k-- {
-		b.Markov[k] = b.Markov[k-1]
-	}
-	b.Markov[0] = s
+// CS is float32 normalized cosine similarity
+func CS(a []float32, b []float32) float32 {
+	return mat.CS(a, b)
 }
 
-// Mix mixes the histograms outputting a matrix
-func (b Basic) Mix() []float32 {
-	x := NewMatrix(b.Length, Size)
-	for i := range b.Histograms {
-		sum := float32(0.0)
-		for _, v := range b.Histograms[i].Vector {
-			sum += float32(v)
-		}
-		for _, v := range b.Histograms[i].Vector {
-			if sum == 0.0 {
-				x.Data = append(x.Data, 0.0)
-				continue
-			}
-			x.Data = append(x.Data, float32(v)/sum)
-		}
-	}
-	return SelfAttention(x)
+func softmax(values []float32) {
+	mat.Softmax(values)
 }
@@ -5,57 +5,101 @@
 package main
 
 import (
-	"math/rand"
+	"math"
+	"path/filepath"
+	"reflect"
 	"testing"
+
+	"github.com/pointlander/textus/mat"
 )
 
-func TestCDF(t *testing.T) {
-	rng := rand.New(rand.NewSource(1))
-	for i := 1; i < 9; i++ {
-		cdf := NewCDF16(true)
-		filtered := cdf(256, i)
-		for j := 0; j < 1024; j++ {
-			filtered.Update(uint16(rng.Intn(256)))
-			t.Log(filtered.GetModel())
+func TestMixerConfig(t *testing.T) {
+	config, err := NewMixerConfig("1,300,1000", 3, "2,4", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMixerWithConfig(256, config)
+	for range 500 {
+		m.Add(7)
+	}
+	if count := m.Histograms[1].Vector[7]; count != 300 {
+		t.Fatalf("%d != 300", count)
+	}
+	if len(m.Markov) != 4 {
+		t.Fatalf("%d != 4", len(m.Markov))
+	}
+	cp := m.Copy()
+	m.Add(8)
+	cp.Add(8)
+	x, y := m.Mix(), cp.Mix()
+	for i, v := range x {
+		if v != y[i] {
+			t.Fatalf("%d: %f != %f", i, v, y[i])
 		}
 	}
+
+	f := NewFilteredWithConfig(config)
+	f.Add(1)
+	if len(f.Mix()) != 256 {
+		t.Fatalf("%d != 256", len(f.Mix()))
+	}
+
+	name := filepath.Join(t.TempDir(), "db.bin")
+	err = SaveMeta(name, Meta{Mixer: config})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := LoadMeta(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(meta.Mixer, config) {
+		t.Fatalf("%v != %v", meta.Mixer, config)
+	}
+
+	_, err = NewMixerConfig("", -1, "0", "")
+	if err == nil {
+		t.Fatal("rate of 0 should be invalid")
+	}
 }
 
-func TestCDFCopy(t *testing.T) {
-	rng := rand.New(rand.NewSource(1))
-	for i := 1; i < 9; i++ {
-		cdf := NewCDF16(true)
-		filtered := cdf(256, i)
-		cp := filtered.Copy()
-		for j := 0; j < 1024; j++ {
-			x := rng.Intn(256)
-			filtered.Update(uint16(x))
-			cp.Update(uint16(x))
-			t.Log(filtered.GetModel())
-		}
-		a, b := filtered.GetModel(), cp.GetModel()
-		for i, v := range a {
-			if v != b[i] {
-				t.Fatalf("%d != %d", v, b[i])
-			}
-		}
+func TestStack(t *testing.T) {
+	meta := Meta{Mixer: DefaultMixerConfig(), Stack: []string{"filtered", "basic", "decayed", "words"}}
+	fused, concat := NewStackWithMeta(meta, mat.Classes(nil)), NewStack(Order, true, NewFiltered(), NewMixer(16))
+	for i := range 100 {
+		fused.Add(byte(i % 7))
+		concat.Add(byte(i % 7))
+	}
+	if fused.Markov[0] != 99%7 {
+		t.Fatalf("%d != %d", fused.Markov[0], 99%7)
+	}
+	cp := fused.Copy()
+	x := fused.Mix()
+	if len(x) != 256 {
+		t.Fatalf("%d != 256", len(x))
+	}
+	fused.Add(3)
+	cp.Add(3)
+	near(t, "stack", 0, fused.Mix(), cp.Mix())
+	y := concat.Mix()
+	if len(y) != 256+16 {
+		t.Fatalf("%d != %d", len(y), 256+16)
+	}
+	if norm := Normalize(append([]float32{}, y...)); CS(norm, y) < .999 {
+		t.Fatal("output is not normalized")
+	}
+
+	meta.Concat = true
+	if z := NewStackWithMeta(meta, mat.Classes(nil)).Mix(); len(z) != 4*256 {
+		t.Fatalf("%d != %d", len(z), 4*256)
 	}
 }
 
-func TestFiltered(t *testing.T) {
-	a := NewFiltered()
-	a.Add(1)
-	a.Add(1)
-	b := NewFiltered()
-	b.Add(1)
-	c := NewFiltered()
-	c.Add(1)
-	x := a.Mix()
-	y := b.Mix()
-	z := c.Mix()
-	i := CS(z[:], x[:])
-	j := CS(z[:], y[:])
-	if j < i {
-		t.Fatalf("%f < %f", j, i)
+func near(t *testing.T, name string, step int, a, b []float32) {
+	t.Helper()
+	for i, v := range a {
+		if diff := math.Abs(float64(v - b[i])); diff > 1e-4 {
+			t.Fatalf("%s %d %d: %f != %f", name, step, i, v, b[i])
+		}
 	}
 }
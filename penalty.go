@@ -0,0 +1,118 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/pointlander/textus/mat"
+)
+
+// Penalty penalizes the symbols generated within a window
+type Penalty struct {
+	// Repetition divides the probability of the symbols in the window if greater than 1
+	Repetition float64
+	// Frequency scales the probability of a symbol by exp(-frequency*count)
+	Frequency float64
+	// Presence scales the probability of the symbols in the window by exp(-presence)
+	Presence float64
+	// NGram blocks the symbols that would repeat an n-gram of the window if greater than 0
+	NGram int
+	// Histogram counts the symbols in the window
+	Histogram mat.Histogram
+	// Count is the number of symbols in the window
+	Count int
+}
+
+// NewPenalty makes a new penalty over a window of generated symbols, the window is at least one symbol
+func NewPenalty(repetition, frequency, presence float64, ngram, window int) *Penalty {
+	if window < 1 {
+		panic(fmt.Errorf("the window of the penalty is %d symbols, it must be at least 1", window))
+	}
+	return &Penalty{
+		Repetition: repetition,
+		Frequency:  frequency,
+		Presence:   presence,
+		NGram:      ngram,
+		Histogram:  mat.NewHistogram(window, 256),
+	}
+}
+
+// FlagPenalty is the penalty selected by the flags, nil if there is no penalty
+func FlagPenalty() *Penalty {
+	if *FlagWindow < 1 {
+		panic(fmt.Errorf("-window is %d, it must be at least 1", *FlagWindow))
+	}
+	if *FlagRepetition <= 1 && *FlagFrequency == 0 && *FlagPresence == 0 && *FlagNGram == 0 {
+		return nil
+	}
+	return NewPenalty(*FlagRepetition, *FlagFrequency, *FlagPresence, *FlagNGram, *FlagWindow)
+}
+
+// Copy copies the penalty
+func (p *Penalty) Copy() *Penalty {
+	cp := *p
+	cp.Histogram = p.Histogram.Copy()
+	return &cp
+}
+
+// Add adds a generated symbol to the window, nothing is removed until the window is full
+func (p *Penalty) Add(s byte) {
+	p.Histogram.Fill(s, p.Count)
+	p.Count = min(p.Count+1, p.Histogram.Size)
+}
+
+// window returns the symbols of the window in order
+func (p *Penalty) window() []byte {
+	h := p.Histogram
+	symbols := make([]byte, 0, p.Count)
+	for i := h.Size - p.Count; i < h.Size; i++ {
+		symbols = append(symbols, h.Buffer[(h.Index+1+i)%h.Size])
+	}
+	return symbols
+}
+
+// Apply applies the penalty to a distribution returning a new distribution,
+// the distribution is returned unchanged if every symbol is penalized to zero
+func (p *Penalty) Apply(distribution []float32) []float32 {
+	output := make([]float32, len(distribution))
+	for i, v := range distribution {
+		count := 0
+		if i < len(p.Histogram.Vector) {
+			count = p.Histogram.Vector[i]
+		}
+		if count == 0 {
+			output[i] = v
+			continue
+		}
+		scale := math.Exp(-p.Frequency*float64(count) - p.Presence)
+		if p.Repetition > 1 {
+			scale /= p.Repetition
+		}
+		output[i] = float32(float64(v) * scale)
+	}
+	if n := p.NGram; n > 0 {
+		if window := p.window(); len(window) >= n {
+			prefix := window[len(window)-n+1:]
+			for i := 0; i+n <= len(window); i++ {
+				if string(window[i:i+n-1]) == string(prefix) && int(window[i+n-1]) < len(output) {
+					output[window[i+n-1]] = 0
+				}
+			}
+		}
+	}
+	sum := float32(0.0)
+	for _, v := range output {
+		sum += v
+	}
+	if sum <= 0 {
+		return distribution
+	}
+	for i := range output {
+		output[i] /= sum
+	}
+	return output
+}
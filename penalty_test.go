@@ -0,0 +1,89 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPenalty(t *testing.T) {
+	uniform := []float32{.25, .25, .25, .25}
+
+	p := NewPenalty(2, 0, 0, 0, 4)
+	p.Add(0)
+	d := p.Apply(uniform)
+	if math.Abs(float64(d[0]*2-d[1])) > 1e-6 {
+		t.Fatalf("repetition not applied: %v", d)
+	}
+
+	p = NewPenalty(1, 1, 0, 0, 4)
+	p.Add(0)
+	p.Add(0)
+	p.Add(1)
+	d = p.Apply(uniform)
+	if math.Abs(float64(d[0])/float64(d[1])-math.Exp(-1)) > 1e-5 {
+		t.Fatalf("frequency not applied: %v", d)
+	}
+
+	p = NewPenalty(2, 0, 0, 0, 2)
+	for _, s := range []byte{0, 1, 2} {
+		p.Add(s)
+	}
+	d = p.Apply(uniform)
+	if d[0] != d[3] || d[1] != d[2] || d[0] <= d[1] {
+		t.Fatalf("window not applied: %v", d)
+	}
+
+	p = NewPenalty(1, 0, 0, 2, 8)
+	for _, s := range []byte{0, 1, 2, 0} {
+		p.Add(s)
+	}
+	d = p.Apply(uniform)
+	if d[1] != 0 || math.Abs(float64(d[0]+d[2]+d[3])-1) > 1e-6 {
+		t.Fatalf("n-gram not blocked: %v", d)
+	}
+
+	p = NewPenalty(1, 0, 0, 1, 8)
+	for _, s := range []byte{0, 1, 2, 3} {
+		p.Add(s)
+	}
+	if d = p.Apply(uniform); d[0] != uniform[0] {
+		t.Fatalf("distribution not returned when every symbol is blocked: %v", d)
+	}
+
+	p = NewPenalty(2, 0, 0, 0, 2)
+	p.Add(1)
+	cp := p.Copy()
+	cp.Add(0)
+	cp.Add(0)
+	if p.Count != 1 || p.Histogram.Vector[0] != 0 || p.Histogram.Vector[1] != 1 {
+		t.Fatalf("the copy changed the penalty: %d %v", p.Count, p.Histogram.Vector[:2])
+	}
+	if cp.Count != 2 || cp.Histogram.Vector[0] != 2 || cp.Histogram.Vector[1] != 0 {
+		t.Fatalf("the copy has a different window: %d %v", cp.Count, cp.Histogram.Vector[:2])
+	}
+	if d, c := p.Apply(uniform), cp.Apply(uniform); d[0] <= d[1] || c[0] >= c[1] {
+		t.Fatalf("the copy isn't independent: %v %v", d, c)
+	}
+
+	for _, window := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("a window of %d doesn't panic", window)
+				}
+			}()
+			NewPenalty(2, 0, 0, 0, window)
+		}()
+	}
+
+	text := NewText(Stop{}, nil)
+	text.Penalty = NewPenalty(1, 0, 100, 0, 4)
+	text.Add(3)
+	if d = text.Apply(uniform); d[3] > 1e-6 {
+		t.Fatalf("text penalty not applied: %v", d)
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+)
+
+const (
+	// PredictorBits is the number of bits in a context model table index
+	PredictorBits = 20
+	// PredictorInputs is the number of inputs to the logistic mixer
+	PredictorInputs = Size + Order + 1
+	// PredictorRate is the learning rate of the logistic mixer
+	PredictorRate = 0.002
+	// PredictorShift is the adaptation shift of the context model counters
+	PredictorShift = 4
+)
+
+func stretch(p float32) float32 {
+	return log(p / (1 - p))
+}
+
+func squash(x float32) float32 {
+	if x > 32 {
+		x = 32
+	} else if x < -32 {
+		x = -32
+	}
+	return 1 / (1 + exp(-x))
+}
+
+// Predictor is a logistic context mixing predictor
+type Predictor struct {
+	Markov  Markov
+	Filters []Filtered16
+	Tables  [Order][]uint16
+	Hashes  [Order]uint32
+	Weights [][PredictorInputs]float32
+	Inputs  [PredictorInputs]float32
+	Q       float32
+	Node    int
+	Bit     int
+	Learn   bool
+}
+
+// NewPredictor makes a new logistic context mixing predictor
+func NewPredictor() *Predictor {
+	cdf := NewCDF16(false)
+	filters := make([]Filtered16, Size)
+	for i := range filters {
+		filters[i] = cdf(256, i+1)
+	}
+	p := &Predictor{
+		Markov:  NewMarkov(Order),
+		Filters: filters,
+		Weights: make([][PredictorInputs]float32, 256),
+		Node:    1,
+		Learn:   true,
+	}
+	for i := range p.Tables {
+		table := make([]uint16, 1<<PredictorBits)
+		for j := range table {
+			table[j] = 1 << 15
+		}
+		p.Tables[i] = table
+	}
+	for i := range p.Weights {
+		for j := range p.Weights[i] {
+			p.Weights[i][j] = .3
+		}
+	}
+	p.hash()
+	return p
+}
+
+// Copy copies the predictor, the copy shares the learned tables and weights and does not learn
+func (p *Predictor) Copy() Mix {
+	filters := make([]Filtered16, len(p.Filters))
+	for i := range filters {
+		filters[i] = p.Filters[i].Copy()
+	}
+	return &Predictor{
+		Markov:  p.Markov.Copy(),
+		Filters: filters,
+		Tables:  p.Tables,
+		Hashes:  p.Hashes,
+		Weights: p.Weights,
+		Node:    p.Node,
+		Bit:     p.Bit,
+	}
+}
+
+// hash computes the context hashes for the order 1 to 7 context models
+func (p *Predictor) hash() {
+	h := uint32(0)
+	for i := range p.Hashes {
+		h = (h+uint32(p.Markov[i])+1)*0x2F0B4677 + uint32(i+1)*0x9E3779B1
+		p.Hashes[i] = h ^ (h >> 15)
+	}
+}
+
+// index computes the table index of a context model for a node
+func (p *Predictor) index(order, node int) uint32 {
+	h := p.Hashes[order] + uint32(node)*0x6F4F2A35
+	h ^= h >> 13
+	return h & (1<<PredictorBits - 1)
+}
+
+// predict computes the probability that the next bit of node is a 1
+func (p *Predictor) predict(node, bit int, inputs *[PredictorInputs]float32) float32 {
+	low := (node << (8 - bit)) & 0xFF
+	mid, high := low+(1<<(7-bit)), low+(1<<(8-bit))
+	for i := range p.Filters {
+		model := p.Filters[i].GetModel()
+		a, b, c := float32(model[low]), float32(model[mid]), float32(model[high])
+		inputs[i] = stretch((c - b) / (c - a))
+	}
+	for i := range p.Tables {
+		q := (float32(p.Tables[i][p.index(i, node)]) + .5) / 65536
+		inputs[Size+i] = stretch(q)
+	}
+	inputs[PredictorInputs-1] = 1
+	weights, dot := &p.Weights[node], float32(0.0)
+	for i, v := range inputs {
+		dot += weights[i] * v
+	}
+	q := squash(dot)
+	if q < 1.0/4096 {
+		q = 1.0 / 4096
+	} else if q > 4095.0/4096 {
+		q = 4095.0 / 4096
+	}
+	return q
+}
+
+// P is the probability that the next bit is a 1
+func (p *Predictor) P() float32 {
+	p.Q = p.predict(p.Node, p.Bit, &p.Inputs)
+	return p.Q
+}
+
+// Update updates the predictor with the next bit, P must be called first
+func (p *Predictor) Update(bit int) {
+	if p.Learn {
+		weights := &p.Weights[p.Node]
+		err := (float32(bit) - p.Q) * PredictorRate
+		for i, v := range p.Inputs {
+			weights[i] += err * v
+		}
+		for i := range p.Tables {
+			table, index := p.Tables[i], p.index(i, p.Node)
+			if bit == 1 {
+				table[index] += (65535 - table[index]) >> PredictorShift
+			} else {
+				table[index] -= table[index] >> PredictorShift
+			}
+		}
+	}
+	p.Node = p.Node<<1 | bit
+	p.Bit++
+	if p.Bit == 8 {
+		s := byte(p.Node)
+		for i := range p.Filters {
+			p.Filters[i].Update(uint16(s))
+		}
+		p.Markov.Add(s)
+		p.Node, p.Bit = 1, 0
+		p.hash()
+	}
+}
+
+// Add adds a symbol to the predictor
+func (p *Predictor) Add(s byte) {
+	for i := 7; i >= 0; i-- {
+		if p.Learn {
+			p.P()
+		}
+		p.Update(int(s>>i) & 1)
+	}
+}
+
+// Mix computes the distribution of the next symbol
+func (p *Predictor) Mix() []float32 {
+	distribution := make([]float32, 256)
+	var inputs [PredictorInputs]float32
+	var walk func(node, bit int, probability float32)
+	walk = func(node, bit int, probability float32) {
+		if bit == 8 {
+			distribution[node&0xFF] = probability
+			return
+		}
+		q := p.predict(node, bit, &inputs)
+		walk(node<<1, bit+1, probability*(1-q))
+		walk(node<<1|1, bit+1, probability*q)
+	}
+	walk(1, 0, 1)
+	return distribution
+}
+
+// Encoder is a binary arithmetic coder
+type Encoder struct {
+	X1, X2 uint32
+	Output []byte
+}
+
+// NewEncoder makes a new binary arithmetic encoder
+func NewEncoder() *Encoder {
+	return &Encoder{
+		X2: math.MaxUint32,
+	}
+}
+
+// Encode encodes a bit with probability p of being a 1
+func (e *Encoder) Encode(bit int, p float32) {
+	q := uint32(p * 4096)
+	xmid := e.X1 + (e.X2-e.X1)>>12*q
+	if bit == 1 {
+		e.X2 = xmid
+	} else {
+		e.X1 = xmid + 1
+	}
+	for (e.X1^e.X2)&0xFF000000 == 0 {
+		e.Output = append(e.Output, byte(e.X2>>24))
+		e.X1 <<= 8
+		e.X2 = e.X2<<8 | 0xFF
+	}
+}
+
+// Flush flushes the encoder
+func (e *Encoder) Flush() []byte {
+	e.Output = append(e.Output, byte(e.X1>>24), 0xFF, 0xFF, 0xFF)
+	return e.Output
+}
+
+// Decoder is a binary arithmetic decoder
+type Decoder struct {
+	X1, X2, X uint32
+	Input     []byte
+}
+
+// NewDecoder makes a new binary arithmetic decoder
+func NewDecoder(input []byte) *Decoder {
+	d := &Decoder{
+		X2:    math.MaxUint32,
+		Input: input,
+	}
+	for range 4 {
+		d.X = d.X<<8 | uint32(d.next())
+	}
+	return d
+}
+
+func (d *Decoder) next() byte {
+	if len(d.Input) == 0 {
+		return 0xFF
+	}
+	b := d.Input[0]
+	d.Input = d.Input[1:]
+	return b
+}
+
+// Decode decodes a bit with probability p of being a 1
+func (d *Decoder) Decode(p float32) int {
+	q := uint32(p * 4096)
+	xmid := d.X1 + (d.X2-d.X1)>>12*q
+	bit := 0
+	if d.X <= xmid {
+		bit = 1
+		d.X2 = xmid
+	} else {
+		d.X1 = xmid + 1
+	}
+	for (d.X1^d.X2)&0xFF000000 == 0 {
+		d.X1 <<= 8
+		d.X2 = d.X2<<8 | 0xFF
+		d.X = d.X<<8 | uint32(d.next())
+	}
+	return bit
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestPredictorCoder(t *testing.T) {
+	input := []byte{}
+	for range 64 {
+		input = append(input, []byte("to be or not to be that is the question ")...)
+	}
+
+	p := NewPredictor()
+	encoder := NewEncoder()
+	for _, s := range input {
+		for i := 7; i >= 0; i-- {
+			bit := int(s>>i) & 1
+			encoder.Encode(bit, p.P())
+			p.Update(bit)
+		}
+	}
+	output := encoder.Flush()
+	if len(output) >= len(input)/4 {
+		t.Fatalf("%d >= %d", len(output), len(input)/4)
+	}
+
+	p = NewPredictor()
+	decoder := NewDecoder(output)
+	for j, s := range input {
+		node := 1
+		for range 8 {
+			bit := decoder.Decode(p.P())
+			p.Update(bit)
+			node = node<<1 | bit
+		}
+		if byte(node) != s {
+			t.Fatalf("%d: %d != %d", j, byte(node), s)
+		}
+	}
+}
+
+func TestPredictorMix(t *testing.T) {
+	p := NewPredictor()
+	for range 32 {
+		for _, s := range []byte("abcabc") {
+			p.Add(s)
+		}
+	}
+	distribution := p.Mix()
+	sum, max, symbol := float32(0.0), float32(0.0), 0
+	for i, v := range distribution {
+		sum += v
+		if v > max {
+			max, symbol = v, i
+		}
+	}
+	if sum < .99 || sum > 1.01 {
+		t.Fatalf("%f is not 1", sum)
+	}
+	if symbol != 'a' {
+		t.Fatalf("%c != a", symbol)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"compress/bzip2"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+const (
+	// ProvenanceContext is the number of runes of source text shown on each side of a corpus position
+	ProvenanceContext = 32
+)
+
+// Documents are the documents of the corpus, the index of a document is its ID
+var Documents = []string{"books/100.txt.utf-8.bz2"}
+
+// LoadDocument loads the runes of a document
+func LoadDocument(document uint32) []rune {
+	file, err := Data.Open(Documents[document])
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(bzip2.NewReader(file))
+	if err != nil {
+		panic(err)
+	}
+	return []rune(string(data))
+}
+
+// Provenance finds the nearest neighbors that voted for each symbol generated after the state of a mixer,
+// at most count neighbors are found for each symbol and the mixer is advanced over the symbols
+func Provenance(items []Item, m Mix, symbols []byte, count int) [][]Neighbor {
+	provenance := make([][]Neighbor, len(symbols))
+	for i, s := range symbols {
+		for _, neighbor := range Search(items, m.Mix(), KNNNeighbors) {
+			if neighbor.Symbol == s && len(provenance[i]) < count {
+				provenance[i] = append(provenance[i], neighbor)
+			}
+		}
+		m.Add(s)
+	}
+	return provenance
+}
+
+// Context renders the source text around an offset of a document with the rune at the offset in brackets
+func Context(document []rune, offset int) string {
+	if offset < 0 || offset >= len(document) {
+		return ""
+	}
+	begin, end := max(0, offset-ProvenanceContext), min(len(document), offset+1+ProvenanceContext)
+	return strconv.Quote(string(document[begin:offset]) + "[" + string(document[offset]) + "]" + string(document[offset+1:end]))
+}
+
+// PrintProvenance prints the corpus positions, similarities and source text of the neighbors of each generated symbol,
+// load loads the runes of a document
+func PrintProvenance(output io.Writer, items []Item, text *Text, provenance [][]Neighbor, load func(document uint32) []rune) {
+	documents := make(map[uint32][]rune)
+	for i, neighbors := range provenance[:len(text.Runes)] {
+		fmt.Fprintf(output, "%d %q\n", i, text.Runes[i])
+		for _, neighbor := range neighbors {
+			source := items[neighbor.Index].Source
+			document, ok := documents[source.Document]
+			if !ok {
+				document = load(source.Document)
+				documents[source.Document] = document
+			}
+			fmt.Fprintf(output, "\t%d:%d %f %s\n", source.Document, source.Offset, neighbor.Similarity,
+				Context(document, int(source.Offset)))
+		}
+	}
+}
+
+// ShowProvenance prints the provenance selected by the flags of text generated after the state of a mixer
+func ShowProvenance(items []Item, m Mix, text *Text) {
+	if *FlagProvenance <= 0 {
+		return
+	}
+	PrintProvenance(os.Stdout, items, text, Provenance(items, m, text.Symbols, *FlagProvenance), LoadDocument)
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestProvenance(t *testing.T) {
+	corpus := []byte(strings.Repeat("to be or not to be, that is the question. ", 4))
+	m := NewFiltered()
+	m.Add(0)
+	items := buildItems(t, m, corpus, 3)
+	for i, item := range items {
+		if item.Source.Document != 3 || int(item.Source.Offset) != i || item.Symbol != corpus[i] {
+			t.Fatalf("%d has source %v", i, item.Source)
+		}
+	}
+
+	m = NewFiltered()
+	m.Add(0)
+	for _, s := range []byte("to be or ") {
+		m.Add(s)
+	}
+	text := NewText(Stop{}, nil).AddAll([]byte("not"))
+	provenance := Provenance(items, m, text.Symbols, 2)
+	for i, neighbors := range provenance {
+		if len(neighbors) == 0 {
+			t.Fatalf("%c has no provenance", text.Symbols[i])
+		}
+		for _, neighbor := range neighbors {
+			if corpus[items[neighbor.Index].Source.Offset] != text.Symbols[i] {
+				t.Fatalf("%c is not at %v", text.Symbols[i], items[neighbor.Index].Source)
+			}
+		}
+	}
+
+	if context := Context([]rune("abcdef"), 2); context != `"ab[c]def"` {
+		t.Fatalf("%s is not the context", context)
+	}
+	if context := Context([]rune("abc"), 3); context != "" {
+		t.Fatalf("%s is out of the document", context)
+	}
+
+	loads := 0
+	load := func(document uint32) []rune {
+		if document != 3 {
+			t.Fatalf("%d is not the document", document)
+		}
+		loads++
+		return []rune(string(corpus))
+	}
+	buffer := bytes.Buffer{}
+	PrintProvenance(&buffer, items, text, provenance, load)
+	if lines := strings.Count(buffer.String(), "\n"); lines <= len(text.Symbols) || loads != 1 {
+		t.Fatalf("%d lines of provenance from %d loads", lines, loads)
+	}
+	if !strings.Contains(buffer.String(), `[n]ot to be`) {
+		t.Fatalf("%s has no source text", buffer.String())
+	}
+}
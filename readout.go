@@ -0,0 +1,174 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/pointlander/gradient/tf64"
+)
+
+// Readout is a linear softmax readout from mixer vectors to symbols
+type Readout struct {
+	Symbols   int
+	Iteration int
+	Set       tf64.Set
+}
+
+// NewReadout makes a new readout for symbols symbols
+func NewReadout(rng *rand.Rand, symbols int) *Readout {
+	set := tf64.NewSet()
+	set.Add("w", InputSize, symbols)
+	set.Add("b", symbols, 1)
+	Initialize(rng, &set)
+
+	return &Readout{
+		Symbols: symbols,
+		Set:     set,
+	}
+}
+
+// Initialize initializes the weights of a set, weights starting with b are biases and are zeroed
+func Initialize(rng *rand.Rand, set *tf64.Set) {
+	for i := range set.Weights {
+		w := set.Weights[i]
+		if strings.HasPrefix(w.N, "b") {
+			w.X = w.X[:cap(w.X)]
+			w.States = make([][]float64, StateTotal)
+			for ii := range w.States {
+				w.States[ii] = make([]float64, len(w.X))
+			}
+			continue
+		}
+		factor := math.Sqrt(2.0 / float64(w.S[0]))
+		for range cap(w.X) {
+			w.X = append(w.X, rng.NormFloat64()*factor)
+		}
+		w.States = make([][]float64, StateTotal)
+		for ii := range w.States {
+			w.States[ii] = make([]float64, len(w.X))
+		}
+	}
+}
+
+// LoadReadout loads a readout from a file
+func LoadReadout(name string) (*Readout, error) {
+	set := tf64.NewSet()
+	_, epoch, err := set.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Readout{
+		Symbols:   set.ByName["b"].S[0],
+		Iteration: epoch,
+		Set:       set,
+	}, nil
+}
+
+// Save saves the readout to a file
+func (r *Readout) Save(name string, cost float64) error {
+	return r.Set.Save(name, cost, r.Iteration)
+}
+
+// Logits computes the logits of the readout
+func (r *Readout) Logits(vector []float32) []float32 {
+	w, b := r.Set.ByName["w"], r.Set.ByName["b"]
+	logits := make([]float32, r.Symbols)
+	for i := range logits {
+		row, sum := w.X[i*InputSize:(i+1)*InputSize], b.X[i]
+		for j, v := range vector {
+			sum += row[j] * float64(v)
+		}
+		logits[i] = float32(sum)
+	}
+	return logits
+}
+
+// Distribution computes the distribution over symbols for a mixer vector
+func (r *Readout) Distribution(vector []float32) []float32 {
+	logits := r.Logits(vector)
+	softmax(logits)
+	return logits
+}
+
+// Train takes one adam step on a batch of vectors and their next symbols
+func (r *Readout) Train(vectors [][]float32, symbols []byte) float64 {
+	others := tf64.NewSet()
+	others.Add("x", InputSize, len(vectors))
+	others.Add("y", r.Symbols, len(vectors))
+	others.Add("ones", r.Symbols, 1)
+	x, y, ones := others.ByName["x"], others.ByName["y"], others.ByName["ones"]
+	for range r.Symbols {
+		ones.X = append(ones.X, 1)
+	}
+	for i, vector := range vectors {
+		for _, v := range vector {
+			x.X = append(x.X, float64(v))
+		}
+		for j := range r.Symbols {
+			if byte(j) == symbols[i] {
+				y.X = append(y.X, -1)
+			} else {
+				y.X = append(y.X, 0)
+			}
+		}
+	}
+
+	// the cross entropy is the log of the sum of the exponentials minus the target logit for each row
+	logits := tf64.Add(tf64.Mul(r.Set.Get("w"), others.Get("x")), r.Set.Get("b"))
+	loss := tf64.Add(tf64.Sum(tf64.Log(tf64.Mul(others.Get("ones"), tf64.Exp(logits)))),
+		tf64.Sum(tf64.Hadamard(logits, others.Get("y"))))
+
+	r.Set.Zero()
+	others.Zero()
+	cost := tf64.Gradient(loss).X[0]
+	if math.IsNaN(cost) || math.IsInf(cost, 0) {
+		return cost
+	}
+	r.Iteration++
+	Adam(&r.Set, r.Iteration)
+	return cost / float64(len(vectors))
+}
+
+// Adam takes an adam step with the gradients of the set
+func Adam(set *tf64.Set, iteration int) {
+	pow := func(x float64) float64 {
+		y := math.Pow(x, float64(iteration))
+		if math.IsNaN(y) || math.IsInf(y, 0) {
+			return 0
+		}
+		return y
+	}
+
+	norm := 0.0
+	for _, p := range set.Weights {
+		for _, d := range p.D {
+			norm += d * d
+		}
+	}
+	norm = math.Sqrt(norm)
+	b1, b2 := pow(B1), pow(B2)
+	scaling := 1.0
+	if norm > 1 {
+		scaling = 1 / norm
+	}
+	for _, w := range set.Weights {
+		for ii, d := range w.D {
+			g := d * scaling
+			m := B1*w.States[StateM][ii] + (1-B1)*g
+			v := B2*w.States[StateV][ii] + (1-B2)*g*g
+			w.States[StateM][ii] = m
+			w.States[StateV][ii] = v
+			mhat := m / (1 - b1)
+			vhat := v / (1 - b2)
+			if vhat < 0 {
+				vhat = 0
+			}
+			w.X[ii] -= Eta * mhat / (math.Sqrt(vhat) + 1e-8)
+		}
+	}
+}
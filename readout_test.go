@@ -0,0 +1,41 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadout(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	r := NewReadout(rng, 4)
+	vectors, symbols := make([][]float32, 0, 8), make([]byte, 0, 8)
+	for i := range 8 {
+		vector := make([]float32, InputSize)
+		vector[i%4] = 1
+		vectors = append(vectors, vector)
+		symbols = append(symbols, byte(i%4))
+	}
+	for range 2048 {
+		r.Train(vectors, symbols)
+	}
+	name := filepath.Join(t.TempDir(), "readout.bin")
+	err := r.Save(name, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = LoadReadout(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, vector := range vectors {
+		distribution := r.Distribution(vector)
+		if p := distribution[symbols[i]]; p < .5 {
+			t.Fatalf("%d: %f < .5", i, p)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// REPLHelp is the help of the repl commands
+const REPLHelp = `a line that isn't a command is added to the text and continued
+:gen [n]            continues the text with n symbols
+:top [k]            shows the k most likely next symbols
+:set name value     sets temperature, topk, topp, length, seed or top, top shows the k most likely symbols at each step
+:rewind [n]         undoes the last n changes to the text
+:branch name        saves a copy of the text as a branch
+:switch name        switches to a copy of a branch
+:branches           lists the branches
+:show               shows the text
+:reset              resets the text to the prompt
+:help               shows this help
+:quit               quits`
+
+// Branch is the text of the repl and the mixer that has been run over it
+type Branch struct {
+	Mix     Mix
+	Symbols []byte
+	// Text carries the penalty and the constraint from one continuation to the next
+	Text *Text
+}
+
+// Copy copies the branch
+func (b Branch) Copy() Branch {
+	return Branch{
+		Mix:     b.Mix.Copy(),
+		Symbols: slices.Clone(b.Symbols),
+		Text:    b.Text.Copy(),
+	}
+}
+
+// REPL is an interactive loop over a loaded machine
+type REPL struct {
+	Forward      map[rune]byte
+	Reverse      map[byte]rune
+	Distribution func(m Mix) []float32
+	Sampler      *Sampler
+	// Text is the text each branch starts with
+	Text *Text
+	// Top is the number of candidates shown at each step
+	Top      int
+	Base     Mix
+	Current  Branch
+	History  []Branch
+	Branches map[string]Branch
+}
+
+// NewREPL makes a new repl starting at the state of a mixer
+func NewREPL(m Mix, distribution func(m Mix) []float32, forward map[rune]byte, reverse map[byte]rune, sampler *Sampler, text *Text) *REPL {
+	return &REPL{
+		Forward:      forward,
+		Reverse:      reverse,
+		Distribution: distribution,
+		Sampler:      sampler,
+		Text:         text,
+		Base:         m,
+		Current:      Branch{Mix: m.Copy(), Text: text.Copy()},
+		Branches:     make(map[string]Branch),
+	}
+}
+
+// String is the text of the current branch
+func (r *REPL) String() string {
+	return NewText(Stop{}, r.Reverse).AddAll(r.Current.Symbols).String()
+}
+
+// change saves the current branch before it is changed
+func (r *REPL) change() {
+	r.History = append(r.History, r.Current.Copy())
+}
+
+// add adds symbols to the current branch
+func (r *REPL) add(symbols []byte) {
+	for _, s := range symbols {
+		r.Current.Mix.Add(s)
+	}
+	r.Current.Symbols = append(r.Current.Symbols, symbols...)
+}
+
+// PrintTop prints the k most likely symbols of a distribution
+func (r *REPL) PrintTop(output io.Writer, distribution []float32, k int) {
+	order := make([]int, len(distribution))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return distribution[order[i]] > distribution[order[j]]
+	})
+	candidates := []string{}
+	for _, i := range order[:min(k, len(order))] {
+		candidates = append(candidates, fmt.Sprintf("%q %.4f", r.Reverse[byte(i)], distribution[i]))
+	}
+	fmt.Fprintln(output, strings.Join(candidates, " "))
+}
+
+// Generate continues the current branch with length symbols and prints the continuation
+func (r *REPL) Generate(output io.Writer, length int) {
+	next := func(m Mix) []float32 {
+		distribution := r.Distribution(m)
+		if r.Top > 0 {
+			r.PrintTop(output, distribution, r.Top)
+		}
+		return distribution
+	}
+	sampler := *r.Sampler
+	sampler.Length = length
+	// a stop removes symbols that were added to the mixer, so the mixer is rebuilt from the start
+	start := r.Current.Mix.Copy()
+	text, m := sampler.Decode(r.Current.Mix, next, r.Current.Text.Continued())
+	if text.Finish == FinishStop {
+		m = start
+		for _, s := range text.Symbols {
+			m.Add(s)
+		}
+	}
+	r.Current.Mix, r.Current.Text = m, text
+	r.Current.Symbols = append(r.Current.Symbols, text.Symbols...)
+	fmt.Fprintln(output, text.String())
+	fmt.Fprintln(output, "finish", text.Finish)
+}
+
+// set sets a sampling parameter
+func (r *REPL) set(name, value string) error {
+	switch name {
+	case "temperature", "topp":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		if name == "temperature" {
+			r.Sampler.Temperature = v
+		} else {
+			r.Sampler.TopP = v
+		}
+	case "topk", "length", "top":
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		switch name {
+		case "topk":
+			r.Sampler.TopK = v
+		case "length":
+			r.Sampler.Length = v
+		case "top":
+			r.Top = v
+		}
+	case "seed":
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		r.Sampler.Rng = rand.New(rand.NewSource(v))
+	default:
+		return fmt.Errorf("%s is not a parameter", name)
+	}
+	return nil
+}
+
+// argument is the optional count argument of a command
+func argument(args []string, value int) (int, error) {
+	if len(args) == 0 {
+		return value, nil
+	}
+	return strconv.Atoi(args[0])
+}
+
+// Execute executes a command returning false if the repl should quit
+func (r *REPL) Execute(output io.Writer, command string) (bool, error) {
+	args := strings.Fields(strings.TrimPrefix(command, ":"))
+	if len(args) == 0 {
+		return true, fmt.Errorf("empty command")
+	}
+	name, args := args[0], args[1:]
+	switch name {
+	case "gen":
+		n, err := argument(args, r.Sampler.Length)
+		if err != nil {
+			return true, err
+		}
+		r.change()
+		r.Generate(output, n)
+	case "top":
+		n, err := argument(args, 8)
+		if err != nil {
+			return true, err
+		}
+		r.PrintTop(output, r.Distribution(r.Current.Mix.Copy()), n)
+	case "set":
+		if len(args) != 2 {
+			return true, fmt.Errorf("set needs a name and a value")
+		}
+		return true, r.set(args[0], args[1])
+	case "rewind":
+		n, err := argument(args, 1)
+		if err != nil {
+			return true, err
+		}
+		if n < 1 || n > len(r.History) {
+			return true, fmt.Errorf("%d changes can't be undone, there are %d", n, len(r.History))
+		}
+		r.Current = r.History[len(r.History)-n]
+		r.History = r.History[:len(r.History)-n]
+		fmt.Fprintln(output, r.String())
+	case "branch":
+		if len(args) != 1 {
+			return true, fmt.Errorf("branch needs a name")
+		}
+		r.Branches[args[0]] = r.Current.Copy()
+	case "switch":
+		if len(args) != 1 {
+			return true, fmt.Errorf("switch needs a name")
+		}
+		branch, ok := r.Branches[args[0]]
+		if !ok {
+			return true, fmt.Errorf("%s is not a branch", args[0])
+		}
+		r.change()
+		r.Current = branch.Copy()
+		fmt.Fprintln(output, r.String())
+	case "branches":
+		names := make([]string, 0, len(r.Branches))
+		for name := range r.Branches {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(output, "%s %q\n", name, NewText(Stop{}, r.Reverse).AddAll(r.Branches[name].Symbols).String())
+		}
+	case "show":
+		fmt.Fprintln(output, r.String())
+	case "reset":
+		r.change()
+		r.Current = Branch{Mix: r.Base.Copy(), Text: r.Text.Copy()}
+	case "help":
+		fmt.Fprintln(output, REPLHelp)
+	case "quit":
+		return false, nil
+	default:
+		return true, fmt.Errorf("%s is not a command, :help shows the commands", name)
+	}
+	return true, nil
+}
+
+// Run reads prompts and commands line by line until the input ends or the repl quits, an error reading the input is printed
+func (r *REPL) Run(input io.Reader, output io.Writer) {
+	scanner := bufio.NewScanner(input)
+	defer func() {
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintln(output, err)
+		}
+	}()
+	fmt.Fprint(output, "> ")
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, ":") {
+			running, err := r.Execute(output, line)
+			if err != nil {
+				fmt.Fprintln(output, err)
+			}
+			if !running {
+				return
+			}
+		} else {
+			symbols := []byte{}
+			for _, v := range line {
+				s, ok := r.Forward[v]
+				if !ok {
+					fmt.Fprintf(output, "%q is not in the alphabet\n", v)
+					continue
+				}
+				symbols = append(symbols, s)
+			}
+			r.change()
+			r.add(symbols)
+			r.Generate(output, r.Sampler.Length)
+		}
+		fmt.Fprint(output, "> ")
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestREPL(t *testing.T) {
+	forward, reverse := make(map[rune]byte), make(map[byte]rune)
+	for i, v := range "abc" {
+		forward[v], reverse[byte(i)] = byte(i), v
+	}
+	distribution := func(m Mix) []float32 {
+		return []float32{.2, .7, .1}
+	}
+	r := NewREPL(NewFiltered(), distribution, forward, reverse, NewSampler(0, 0, 1, 3, 1, 1), NewText(Stop{}, reverse))
+	output := bytes.Buffer{}
+	r.Run(strings.NewReader("ab\n:branch one\n:gen 2\n:show\n:rewind\n:switch one\n:rewind 3\n:set top 2\n:set length 1\nc\n:top 1\n:set bogus 1\n:branches\n:quit\n:show\n"), &output)
+	lines := strings.Split(output.String(), "\n")
+	expected := []string{
+		"> bbb",
+		"finish length",
+		"> > bb",
+		"finish length",
+		"> abbbbbb",
+		"> abbbb",
+		"> abbbb",
+		"> 3 changes can't be undone, there are 2",
+		"> > > 'b' 0.7000 'a' 0.2000",
+		"b",
+		"finish length",
+		"> 'b' 0.7000",
+		"> bogus is not a parameter",
+		"> one \"abbbb\"",
+		"> ",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("%q", lines)
+	}
+	for i := range lines {
+		if lines[i] != expected[i] {
+			t.Fatalf("%d %q != %q", i, lines[i], expected[i])
+		}
+	}
+	if r.String() != "abbbbcb" {
+		t.Fatalf("%q is not the text", r.String())
+	}
+}
+
+func TestREPLText(t *testing.T) {
+	forward, reverse := make(map[rune]byte), make(map[byte]rune)
+	for i, v := range "abc" {
+		forward[v], reverse[byte(i)] = byte(i), v
+	}
+	distribution := func(m Mix) []float32 {
+		return []float32{.2, .7, .1}
+	}
+	dfa, err := NewDFA("cab*", reverse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := NewText(Stop{}, reverse)
+	text.Constraint = NewConstraint(dfa)
+	r := NewREPL(NewFiltered(), distribution, forward, reverse, NewSampler(0, 0, 1, 3, 1, 1), text)
+	output := bytes.Buffer{}
+	r.Run(strings.NewReader(":gen 1\n:branch one\n:gen 1\n:switch one\n:gen 2\n:reset\n:gen 1\n"), &output)
+	if r.String() != "c" {
+		t.Fatalf("%q is not the text", r.String())
+	}
+	if branch := NewText(Stop{}, reverse).AddAll(r.History[len(r.History)-2].Symbols).String(); branch != "cab" {
+		t.Fatalf("%q doesn't continue the constraint of the branch", branch)
+	}
+	if text.Constraint.State != dfa.Start {
+		t.Fatal("the text of the repl was changed")
+	}
+
+	output.Reset()
+	r.Run(iotest.ErrReader(errors.New("broken")), &output)
+	if !strings.HasSuffix(output.String(), "broken\n") {
+		t.Fatalf("%q doesn't report the error", output.String())
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+)
+
+// Sampler samples symbols from the distributions produced by a model
+type Sampler struct {
+	// Temperature scales the distribution, 0 is greedy
+	Temperature float64
+	// TopK keeps the k most likely symbols if greater than 0
+	TopK int
+	// TopP keeps the most likely symbols with a total probability of p if less than 1
+	TopP float64
+	// Length is the number of symbols generated
+	Length int
+	// Samples is the number of samples generated
+	Samples int
+	Rng     *rand.Rand
+}
+
+// NewSampler makes a new sampler
+func NewSampler(temperature float64, topK int, topP float64, length, samples int, seed int64) *Sampler {
+	return &Sampler{
+		Temperature: temperature,
+		TopK:        topK,
+		TopP:        topP,
+		Length:      length,
+		Samples:     samples,
+		Rng:         rand.New(rand.NewSource(seed)),
+	}
+}
+
+// FlagSampler is the sampler selected by the flags, length and samples are the defaults of the machine
+func FlagSampler(length, samples int) *Sampler {
+	if *FlagLength > 0 {
+		length = *FlagLength
+	}
+	if *FlagSamples > 0 {
+		samples = *FlagSamples
+	}
+	return NewSampler(*FlagTemperature, *FlagTopK, *FlagTopP, length, samples, *FlagSeed)
+}
+
+// Confident is the sampler with the temperature scaled down by a confidence between 0 and 1
+func (s *Sampler) Confident(confidence float64) *Sampler {
+	cp := *s
+	cp.Temperature *= 1 - confidence
+	return &cp
+}
+
+// Filter applies the temperature, top-k and top-p to a distribution returning a new distribution
+func (s *Sampler) Filter(distribution []float32) []float32 {
+	output := make([]float32, len(distribution))
+	if len(distribution) == 0 {
+		return output
+	}
+	order := make([]int, len(distribution))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return distribution[order[i]] > distribution[order[j]]
+	})
+	if s.Temperature <= 0 {
+		output[order[0]] = 1
+		return output
+	}
+	keep := len(order)
+	if s.TopK > 0 && s.TopK < keep {
+		keep = s.TopK
+	}
+	if s.TopP > 0 && s.TopP < 1 {
+		total := 0.0
+		for _, i := range order[:keep] {
+			total += float64(distribution[i])
+		}
+		sum := 0.0
+		for j, i := range order[:keep] {
+			sum += float64(distribution[i])
+			if sum >= s.TopP*total {
+				keep = j + 1
+				break
+			}
+		}
+	}
+	sum := 0.0
+	for _, i := range order[:keep] {
+		p := math.Pow(float64(distribution[i]), 1/s.Temperature)
+		output[i] = float32(p)
+		sum += p
+	}
+	if sum == 0 || math.IsInf(sum, 0) || math.IsNaN(sum) {
+		clear(output)
+		output[order[0]] = 1
+		return output
+	}
+	for _, i := range order[:keep] {
+		output[i] = float32(float64(output[i]) / sum)
+	}
+	return output
+}
+
+// Sample samples a symbol from the filtered distribution returning the symbol and its filtered probability
+func (s *Sampler) Sample(distribution []float32) (byte, float32) {
+	filtered := s.Filter(distribution)
+	sum, selected, symbol := float32(0.0), s.Rng.Float32(), -1
+	for i, c := range filtered {
+		if c == 0 {
+			continue
+		}
+		sum += c
+		symbol = i
+		if selected < sum {
+			break
+		}
+	}
+	if symbol < 0 {
+		return 0, 0
+	}
+	return byte(symbol), filtered[symbol]
+}
+
+// Generate samples symbols from the distributions of a mixer into text until the length or a stop condition is reached
+func (s *Sampler) Generate(m Mix, distribution func(m Mix) []float32, text *Text) *Text {
+	text, _ = s.Decode(m, distribution, text)
+	return text
+}
+
+// Decode is Generate returning the mixer after the generated symbols,
+// the penalty and the constraint of the text are applied to each distribution,
+// the previous symbol is replaced up to Backtracks times when the constraint masks every symbol and the mixer is then a copy,
+// the temperature is scaled down by the confidence of a Confident mixer
+// the proposal of a Proposer is generated instead of a sample if the text allows it
+// and generation stops when the context of the text is done
+func (s *Sampler) Decode(m Mix, distribution func(m Mix) []float32, text *Text) (*Text, Mix) {
+	type Step struct {
+		Mix          Mix
+		Text         *Text
+		Distribution []float32
+		// Proposed is the proposed symbol that was generated or -1
+		Proposed int
+	}
+	steps, backtracks := []Step{}, 0
+	var d []float32
+	for i := 0; i < s.Length; i++ {
+		if text.Canceled() {
+			text.Finish = FinishCanceled
+			break
+		}
+		proposed := -1
+		if d == nil {
+			if p, ok := m.(Proposer); ok {
+				if symbol, ok := p.Propose(); ok {
+					onehot := make([]float32, int(symbol)+1)
+					onehot[symbol] = 1
+					if d = text.Apply(onehot); mass(d) > 0 {
+						proposed = int(symbol)
+					} else {
+						d = nil
+					}
+				}
+			}
+		}
+		if d == nil {
+			d = text.Apply(distribution(m))
+		}
+		for text.Constraint != nil && mass(d) == 0 && len(steps) > 0 && backtracks < Backtracks {
+			step := steps[len(steps)-1]
+			steps = steps[:len(steps)-1]
+			m, text, d = step.Mix, step.Text, step.Distribution
+			if step.Proposed >= 0 {
+				d = text.Apply(distribution(m))
+				if step.Proposed < len(d) {
+					d[step.Proposed] = 0
+				}
+			}
+			proposed = -1
+			i--
+			backtracks++
+		}
+		symbol, _ := s.Confident(confidence(m)).Sample(d)
+		if text.Constraint != nil {
+			rest := slices.Clone(d)
+			rest[symbol] = 0
+			steps = append(steps, Step{m.Copy(), text.Copy(), rest, proposed})
+		}
+		if text.Add(symbol) {
+			break
+		}
+		m.Add(symbol)
+		d = nil
+	}
+	text.Flush()
+	return text, m
+}
+
+// Tally is a mixer that sums the votes of the symbols added to it, the votes of the next symbol are set by the distribution function
+type Tally struct {
+	Mixer Mix
+	// Votes are the votes of the next symbol
+	Votes []float32
+	// Sum is the sum of the votes of the added symbols
+	Sum float64
+}
+
+// Copy copies the tally
+func (t *Tally) Copy() Mix {
+	return &Tally{Mixer: t.Mixer.Copy(), Votes: t.Votes, Sum: t.Sum}
+}
+
+// Add adds the vote of a symbol to the sum and the symbol to the mixer
+func (t *Tally) Add(s byte) {
+	if int(s) < len(t.Votes) {
+		t.Sum += float64(t.Votes[s])
+	}
+	t.Votes = nil
+	t.Mixer.Add(s)
+}
+
+// Mix is the output of the mixer
+func (t *Tally) Mix() []float32 {
+	return t.Mixer.Mix()
+}
+
+// Confidence is the confidence of the mixer
+func (t *Tally) Confidence() float64 {
+	return confidence(t.Mixer)
+}
+
+// mass is the total probability of a distribution
+func mass(distribution []float32) float32 {
+	sum := float32(0.0)
+	for _, v := range distribution {
+		sum += v
+	}
+	return sum
+}
@@ -0,0 +1,93 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestSampler(t *testing.T) {
+	distribution := []float32{.1, .4, .2, .3}
+	nonzero := func(d []float32) int {
+		count, sum := 0, float32(0.0)
+		for _, v := range d {
+			if v > 0 {
+				count++
+			}
+			sum += v
+		}
+		if sum < .999 || sum > 1.001 {
+			t.Fatalf("%f is not 1", sum)
+		}
+		return count
+	}
+	if d := NewSampler(0, 0, 1, 1, 1, 1).Filter(distribution); nonzero(d) != 1 || d[1] != 1 {
+		t.Fatalf("greedy %v", d)
+	}
+	if d := NewSampler(1, 2, 1, 1, 1, 1).Filter(distribution); nonzero(d) != 2 || d[0] != 0 || d[2] != 0 {
+		t.Fatalf("top-k %v", d)
+	}
+	if d := NewSampler(1, 0, .65, 1, 1, 1).Filter(distribution); nonzero(d) != 2 || d[1] == 0 || d[3] == 0 {
+		t.Fatalf("top-p %v", d)
+	}
+	if d := NewSampler(.5, 0, 1, 1, 1, 1).Filter(distribution); d[1] <= distribution[1] || d[0] >= distribution[0] {
+		t.Fatalf("temperature %v", d)
+	}
+
+	a, b := NewSampler(1, 0, 1, 64, 1, 7), NewSampler(1, 0, 1, 64, 1, 7)
+	x := a.Generate(NewFiltered(), func(m Mix) []float32 { return distribution }, NewText(Stop{}, nil)).Symbols
+	y := b.Generate(NewFiltered(), func(m Mix) []float32 { return distribution }, NewText(Stop{}, nil)).Symbols
+	if len(x) != 64 || string(x) != string(y) {
+		t.Fatalf("%v != %v", x, y)
+	}
+}
+
+func TestCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls, text := 0, NewText(Stop{}, nil)
+	text.Context = ctx
+	next := func(m Mix) []float32 {
+		if calls++; calls == 2 {
+			cancel()
+		}
+		return m.Mix()
+	}
+	text = NewSampler(0, 0, 1, 8, 1, 1).Generate(&chain{Last: 2}, next, text)
+	if text.Finish != FinishCanceled || len(text.Symbols) != 2 || calls != 2 {
+		t.Fatalf("%v %d symbols %d calls", text.Finish, len(text.Symbols), calls)
+	}
+}
+
+func TestTally(t *testing.T) {
+	next := func(m Mix) []float32 {
+		tally := m.(*Tally)
+		tally.Votes = tally.Mix()
+		return tally.Votes
+	}
+	dfa, err := NewDFA("\\x00\\x01\\x02|\\x01\\x01\\x01", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		Constraint *Constraint
+		Text       string
+		Sum        float64
+	}{
+		{nil, "\x00\x02\x00", 1.6},
+		{NewConstraint(dfa), "\x01\x01\x01", 1.4},
+	} {
+		text := NewText(Stop{}, nil)
+		text.Constraint = test.Constraint
+		text, m := NewSampler(0, 0, 1, 3, 1, 1).Decode(&Tally{Mixer: &chain{Last: 2}}, next, text)
+		if text.String() != test.Text {
+			t.Fatalf("%q != %q", text.String(), test.Text)
+		}
+		if sum := m.(*Tally).Sum; math.Abs(sum-test.Sum) > 1e-6 {
+			t.Fatalf("%f != %f", sum, test.Sum)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+const (
+	// Smoothing is the weight of the uniform distribution mixed into a distribution that is scored
+	Smoothing = 1e-3
+)
+
+// LogProbs are the log probabilities of symbols following the state of a mixer, the mixer is advanced over the symbols
+func LogProbs(m Mix, distribution func(m Mix) []float32, symbols []byte) []float64 {
+	logprobs := make([]float64, len(symbols))
+	for i, s := range symbols {
+		d := distribution(m)
+		p := 0.0
+		if int(s) < len(d) {
+			p = float64(d[s])
+		}
+		logprobs[i] = math.Log((1-Smoothing)*p + Smoothing/float64(len(d)))
+		m.Add(s)
+	}
+	return logprobs
+}
+
+// Candidate is a scored candidate continuation of a prompt
+type Candidate struct {
+	Text     string    `json:"text"`
+	LogProbs []float64 `json:"logprobs"`
+	Total    float64   `json:"total"`
+	// Mean is the mean log probability of the characters, 0 for an empty candidate
+	Mean float64 `json:"mean"`
+}
+
+// Score scores candidate continuations of a prompt, each candidate is fed through a copy of the mixer after the prompt
+// and the candidates are returned in order
+func (m Model) Score(prompt string, candidates []string) ([]Candidate, error) {
+	start, err := m.Start(prompt)
+	if err != nil {
+		return nil, err
+	}
+	scored := make([]Candidate, len(candidates))
+	for i, candidate := range candidates {
+		symbols, err := m.Encode(candidate)
+		if err != nil {
+			return nil, err
+		}
+		scored[i] = Candidate{
+			Text:     candidate,
+			LogProbs: LogProbs(start.Copy(), m.Distribution, symbols),
+		}
+		for _, logprob := range scored[i].LogProbs {
+			scored[i].Total += logprob
+		}
+		if n := len(scored[i].LogProbs); n > 0 {
+			scored[i].Mean = scored[i].Total / float64(n)
+		}
+	}
+	return scored, nil
+}
+
+// Rank sorts candidates from the most to the least likely by the mean log probability of their characters
+// with the empty candidates last, or by the total log probability which favors short candidates
+func Rank(candidates []Candidate, total bool) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if total {
+			return candidates[i].Total > candidates[j].Total
+		}
+		a, b := len(candidates[i].LogProbs) > 0, len(candidates[j].LogProbs) > 0
+		if a != b {
+			return a
+		}
+		return candidates[i].Mean > candidates[j].Mean
+	})
+}
+
+// PrintCandidates prints the total, mean and per character log probabilities of candidates
+func PrintCandidates(output io.Writer, candidates []Candidate) {
+	for _, candidate := range candidates {
+		fmt.Fprintf(output, "%f %f %q\n", candidate.Total, candidate.Mean, candidate.Text)
+		for i, r := range []rune(candidate.Text) {
+			fmt.Fprintf(output, "\t%q %f\n", r, candidate.LogProbs[i])
+		}
+	}
+}
+
+// ScoreCandidates scores the candidates selected by the flags or read from standard input one per line,
+// the mixer of the model has been run over the prompt
+func ScoreCandidates(model Model) {
+	candidates := []string(FlagCandidates)
+	if len(candidates) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			candidates = append(candidates, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			panic(err)
+		}
+	}
+	scored, err := model.Score("", candidates)
+	if err != nil {
+		panic(err)
+	}
+	Rank(scored, *FlagTotal)
+	PrintCandidates(os.Stdout, scored)
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	forward, reverse := make(map[rune]byte), make(map[byte]rune)
+	for i, v := range "abc" {
+		forward[v], reverse[byte(i)] = byte(i), v
+	}
+	model := Model{
+		Mix: NewFiltered(),
+		Distribution: func(m Mix) []float32 {
+			return []float32{.2, .8, 0}
+		},
+		Forward: forward,
+		Reverse: reverse,
+	}
+	scored, err := model.Score("ab", []string{"ab", "bb", "c", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := func(p float64) float64 {
+		return math.Log((1-Smoothing)*p + Smoothing/3)
+	}
+	expected := []float64{p(.2) + p(.8), 2 * p(.8), p(0), 0}
+	for i, candidate := range scored {
+		if len(candidate.LogProbs) != len(candidate.Text) || math.Abs(candidate.Total-expected[i]) > 1e-6 {
+			t.Fatalf("%q %v", candidate.Text, candidate)
+		}
+		if n := len(candidate.Text); n > 0 && math.Abs(candidate.Mean-expected[i]/float64(n)) > 1e-6 {
+			t.Fatalf("%q %f is not the mean", candidate.Text, candidate.Mean)
+		}
+	}
+	if math.IsInf(scored[2].Total, 0) {
+		t.Fatal("an impossible candidate should be smoothed")
+	}
+	if _, err := model.Score("x", nil); err == nil {
+		t.Fatal("x is not in the alphabet")
+	}
+	if _, err := model.Score("", []string{"ax"}); err == nil {
+		t.Fatal("x is not in the alphabet")
+	}
+
+	for _, test := range []struct {
+		Total bool
+		Order []string
+	}{
+		{false, []string{"bb", "ab", "c", ""}},
+		{true, []string{"", "bb", "ab", "c"}},
+	} {
+		Rank(scored, test.Total)
+		for i, text := range test.Order {
+			if scored[i].Text != text {
+				t.Fatalf("%t %v is not ranked", test.Total, scored)
+			}
+		}
+	}
+	output := bytes.Buffer{}
+	PrintCandidates(&output, scored)
+	if lines := strings.Count(output.String(), "\n"); lines != 4+5 {
+		t.Fatalf("%d lines", lines)
+	}
+}
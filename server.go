@@ -0,0 +1,382 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// ServerMaxLength is the maximum number of symbols generated for a request
+	ServerMaxLength = 4096
+	// ServerMaxBody is the maximum size of a request body in bytes
+	ServerMaxBody = 1 << 20
+	// ServerShutdown is how long the server waits for requests to finish when shutting down
+	ServerShutdown = 10 * time.Second
+)
+
+// Model is a machine that is loaded once and used by many requests
+type Model struct {
+	// Mix is the mixer at the start of a text, it is copied for each request
+	Mix          Mix
+	Distribution func(m Mix) []float32
+	Forward      map[rune]byte
+	Reverse      map[byte]rune
+}
+
+// Encode encodes text into symbols
+func (m Model) Encode(text string) ([]byte, error) {
+	symbols := make([]byte, 0, len(text))
+	for _, v := range text {
+		s, ok := m.Forward[v]
+		if !ok {
+			return nil, fmt.Errorf("%q is not in the alphabet", v)
+		}
+		symbols = append(symbols, s)
+	}
+	return symbols, nil
+}
+
+// Start is a copy of the mixer after the prompt
+func (m Model) Start(prompt string) (Mix, error) {
+	symbols, err := m.Encode(prompt)
+	if err != nil {
+		return nil, err
+	}
+	mix := m.Mix.Copy()
+	for _, s := range symbols {
+		mix.Add(s)
+	}
+	return mix, nil
+}
+
+// GenerateRequest is a request to the generate endpoint, unset parameters are the defaults of the server
+type GenerateRequest struct {
+	Prompt      string   `json:"prompt"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopK        *int     `json:"top_k,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	Length      *int     `json:"length,omitempty"`
+	Seed        *int64   `json:"seed,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Line        *bool    `json:"line,omitempty"`
+	Paragraph   *bool    `json:"paragraph,omitempty"`
+	Repetition  *float64 `json:"repetition,omitempty"`
+	Frequency   *float64 `json:"frequency,omitempty"`
+	Presence    *float64 `json:"presence,omitempty"`
+	NGram       *int     `json:"ngram,omitempty"`
+	Constraint  *string  `json:"constraint,omitempty"`
+	// Stream streams the symbols as server sent events
+	Stream bool `json:"stream,omitempty"`
+}
+
+// GenerateResponse is the response of the generate endpoint
+type GenerateResponse struct {
+	Text   string `json:"text"`
+	Finish Finish `json:"finish"`
+}
+
+// StreamEvent is a server sent event of a generated symbol, the symbols that could be the start of a stop are held back
+// and a symbol can be replaced by backtracking so the text of the done event is the generated text
+type StreamEvent struct {
+	Index int    `json:"index"`
+	Text  string `json:"text"`
+}
+
+// ScoreRequest is a request to the score endpoint
+type ScoreRequest struct {
+	Prompt     string   `json:"prompt"`
+	Text       string   `json:"text"`
+	Candidates []string `json:"candidates,omitempty"`
+	// Total ranks the candidates by total log probability instead of mean log probability
+	Total bool `json:"total,omitempty"`
+}
+
+// ScoreResponse is the response of the score endpoint, the candidates are ranked from the most to the least likely
+type ScoreResponse struct {
+	LogProbs   []float64   `json:"logprobs"`
+	Total      float64     `json:"total"`
+	Mean       float64     `json:"mean"`
+	Candidates []Candidate `json:"candidates,omitempty"`
+}
+
+// EmbedRequest is a request to the embed endpoint
+type EmbedRequest struct {
+	Text string `json:"text"`
+}
+
+// EmbedResponse is the response of the embed endpoint
+type EmbedResponse struct {
+	Embedding []float32 `json:"embedding"`
+}
+
+// ErrorResponse is the response of a failed request
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server serves a model over http
+type Server struct {
+	Model Model
+	// Sampler has the default sampling parameters
+	Sampler *Sampler
+	// Text has the default stop conditions, penalty and constraint, it is copied for each request
+	Text *Text
+	// Limit limits the number of concurrent requests
+	Limit chan struct{}
+	mutex sync.Mutex
+}
+
+// NewServer makes a new server that handles at most concurrency requests at a time
+func NewServer(model Model, sampler *Sampler, text *Text, concurrency int) *Server {
+	return &Server{
+		Model:   model,
+		Sampler: sampler,
+		Text:    text,
+		Limit:   make(chan struct{}, concurrency),
+	}
+}
+
+// Handler is the http handler of the endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /generate", s.limit(s.generate))
+	mux.HandleFunc("POST /score", s.limit(s.score))
+	mux.HandleFunc("POST /embed", s.limit(s.embed))
+	return mux
+}
+
+// limit rejects requests when the server is handling too many
+func (s *Server) limit(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case s.Limit <- struct{}{}:
+			defer func() {
+				<-s.Limit
+			}()
+			handler(w, r)
+		default:
+			writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{Error: "too many requests"})
+		}
+	}
+}
+
+// writeJSON writes a json response
+func writeJSON(w http.ResponseWriter, status int, response any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// decode decodes a json request of at most ServerMaxBody bytes writing an error response if it fails
+func decode(w http.ResponseWriter, r *http.Request, request any) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, ServerMaxBody)).Decode(request)
+	if err != nil {
+		status, tooLarge := http.StatusBadRequest, &http.MaxBytesError{}
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeJSON(w, status, ErrorResponse{Error: err.Error()})
+		return false
+	}
+	return true
+}
+
+// sampler is the sampler of a request
+func (s *Server) sampler(request *GenerateRequest) (*Sampler, error) {
+	s.mutex.Lock()
+	seed := s.Sampler.Rng.Int63()
+	s.mutex.Unlock()
+	if request.Seed != nil {
+		seed = *request.Seed
+	}
+	sampler := NewSampler(s.Sampler.Temperature, s.Sampler.TopK, s.Sampler.TopP, s.Sampler.Length, 1, seed)
+	if request.Temperature != nil {
+		sampler.Temperature = *request.Temperature
+	}
+	if request.TopK != nil {
+		sampler.TopK = *request.TopK
+	}
+	if request.TopP != nil {
+		sampler.TopP = *request.TopP
+	}
+	if request.Length != nil {
+		sampler.Length = *request.Length
+	}
+	if sampler.Length < 0 || sampler.Length > ServerMaxLength {
+		return nil, fmt.Errorf("length %d is not between 0 and %d", sampler.Length, ServerMaxLength)
+	}
+	return sampler, nil
+}
+
+// text is the generated text of a request
+func (s *Server) text(request *GenerateRequest) (*Text, error) {
+	text := s.Text.Copy()
+	if request.Stop != nil {
+		text.Stop.Strings = request.Stop
+	}
+	if request.Line != nil {
+		text.Stop.Line = *request.Line
+	}
+	if request.Paragraph != nil {
+		text.Stop.Paragraph = *request.Paragraph
+	}
+	if request.Repetition != nil || request.Frequency != nil || request.Presence != nil || request.NGram != nil {
+		if text.Penalty == nil {
+			text.Penalty = NewPenalty(1, 0, 0, 0, *FlagWindow)
+		}
+		if request.Repetition != nil {
+			text.Penalty.Repetition = *request.Repetition
+		}
+		if request.Frequency != nil {
+			text.Penalty.Frequency = *request.Frequency
+		}
+		if request.Presence != nil {
+			text.Penalty.Presence = *request.Presence
+		}
+		if request.NGram != nil {
+			text.Penalty.NGram = *request.NGram
+		}
+	}
+	if request.Constraint != nil {
+		text.Constraint = nil
+		if *request.Constraint != "" {
+			dfa, err := NewDFA(*request.Constraint, s.Model.Reverse)
+			if err != nil {
+				return nil, err
+			}
+			text.Constraint = NewConstraint(dfa)
+		}
+	}
+	return text, nil
+}
+
+// generate generates text after a prompt, generation stops without a response when the request is canceled
+func (s *Server) generate(w http.ResponseWriter, r *http.Request) {
+	request := GenerateRequest{}
+	if !decode(w, r, &request) {
+		return
+	}
+	sampler, err := s.sampler(&request)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	text, err := s.text(&request)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	m, err := s.Model.Start(request.Prompt)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	ctx := r.Context()
+	text.Context = ctx
+	if !request.Stream {
+		text = sampler.Generate(m, s.Model.Distribution, text)
+		if ctx.Err() != nil {
+			return
+		}
+		writeJSON(w, http.StatusOK, GenerateResponse{Text: text.String(), Finish: text.Finish})
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "streaming is not supported"})
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	event := func(name string, data any) {
+		if ctx.Err() != nil {
+			return
+		}
+		encoded, _ := json.Marshal(data)
+		if name != "" {
+			fmt.Fprintf(w, "event: %s\n", name)
+		}
+		fmt.Fprintf(w, "data: %s\n\n", encoded)
+		flusher.Flush()
+	}
+	text.Stream = func(index int, r rune) {
+		event("", StreamEvent{Index: index, Text: string(r)})
+	}
+	text = sampler.Generate(m, s.Model.Distribution, text)
+	event("done", GenerateResponse{Text: text.String(), Finish: text.Finish})
+}
+
+// score scores the log probability of each symbol of text and of the candidates after a prompt
+func (s *Server) score(w http.ResponseWriter, r *http.Request) {
+	request := ScoreRequest{}
+	if !decode(w, r, &request) {
+		return
+	}
+	scored, err := s.Model.Score(request.Prompt, append([]string{request.Text}, request.Candidates...))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	response := ScoreResponse{LogProbs: scored[0].LogProbs, Total: scored[0].Total, Mean: scored[0].Mean}
+	if len(scored) > 1 {
+		response.Candidates = scored[1:]
+		Rank(response.Candidates, request.Total)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// embed embeds text as the mixer vector after the text
+func (s *Server) embed(w http.ResponseWriter, r *http.Request) {
+	request := EmbedRequest{}
+	if !decode(w, r, &request) {
+		return
+	}
+	m, err := s.Model.Start(request.Text)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, EmbedResponse{Embedding: m.Mix()})
+}
+
+// Serve serves a model on an address until an interrupt or terminate signal, then waits for the requests to finish
+// and closes the connections that are still open after ServerShutdown
+func Serve(address string, server *Server) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	s := &http.Server{
+		Addr:    address,
+		Handler: server.Handler(),
+	}
+	done := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), ServerShutdown)
+		defer cancel()
+		done <- s.Shutdown(shutdown)
+	}()
+	fmt.Fprintln(os.Stderr, "serving on", address)
+	err := s.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		panic(err)
+	}
+	err = <-done
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "shutdown", err)
+		s.Close()
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	forward, reverse := make(map[rune]byte), make(map[byte]rune)
+	for i, v := range "abc" {
+		forward[v], reverse[byte(i)] = byte(i), v
+	}
+	model := Model{
+		Mix: NewFiltered(),
+		Distribution: func(m Mix) []float32 {
+			return []float32{.2, .7, .1}
+		},
+		Forward: forward,
+		Reverse: reverse,
+	}
+	server := NewServer(model, NewSampler(0, 0, 1, 4, 1, 1), NewText(Stop{Strings: []string{"cc"}}, reverse), 2)
+	s := httptest.NewServer(server.Handler())
+	defer s.Close()
+
+	post := func(path, body string, response any) int {
+		r, err := http.Post(s.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+		err = json.NewDecoder(r.Body).Decode(response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.StatusCode
+	}
+
+	generated := GenerateResponse{}
+	if status := post("/generate", `{"prompt": "ab", "length": 3}`, &generated); status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+	if generated.Text != "bbb" || generated.Finish != FinishLength {
+		t.Fatalf("%v", generated)
+	}
+	post("/generate", `{"prompt": "a", "stop": ["bb"]}`, &generated)
+	if generated.Text != "" || generated.Finish != FinishStop {
+		t.Fatalf("%v", generated)
+	}
+	post("/generate", `{"prompt": "a", "constraint": "ca*"}`, &generated)
+	if generated.Text != "caaa" {
+		t.Fatalf("%v", generated)
+	}
+	post("/generate", `{"prompt": "a", "constraint": "c*"}`, &generated)
+	if generated.Text != "" || generated.Finish != FinishStop {
+		t.Fatalf("the default stop is not applied %v", generated)
+	}
+	if len(server.Text.Stop.Strings) != 1 || len(server.Text.Symbols) != 0 || server.Text.Constraint != nil {
+		t.Fatalf("the default text was changed %v", server.Text)
+	}
+
+	failed := ErrorResponse{}
+	for _, body := range []string{`{"prompt": "x"}`, `{"length": -1}`, `{"constraint": "("}`, `{"constraint": "[ab]*a[ab]{16}"}`, `{`} {
+		if status := post("/generate", body, &failed); status != http.StatusBadRequest || failed.Error == "" {
+			t.Fatalf("%s has status %d", body, status)
+		}
+	}
+	large := `{"prompt": "` + strings.Repeat("a", ServerMaxBody) + `"}`
+	if status := post("/generate", large, &failed); status != http.StatusRequestEntityTooLarge || failed.Error == "" {
+		t.Fatalf("a large body has status %d", status)
+	}
+
+	scored := ScoreResponse{}
+	post("/score", `{"prompt": "a", "text": "bc"}`, &scored)
+	if len(scored.LogProbs) != 2 || math.Abs(scored.Total-scored.LogProbs[0]-scored.LogProbs[1]) > 1e-9 ||
+		scored.LogProbs[0] <= scored.LogProbs[1] {
+		t.Fatalf("%v", scored)
+	}
+
+	post("/score", `{"prompt": "a", "candidates": ["c", "b"]}`, &scored)
+	if len(scored.LogProbs) != 0 || len(scored.Candidates) != 2 || scored.Candidates[0].Text != "b" {
+		t.Fatalf("%v", scored)
+	}
+	post("/score", `{"prompt": "a", "candidates": ["", "bb"]}`, &scored)
+	if scored.Candidates[0].Text != "bb" {
+		t.Fatalf("%v is not ranked by the mean", scored)
+	}
+	post("/score", `{"prompt": "a", "candidates": ["", "bb"], "total": true}`, &scored)
+	if scored.Candidates[0].Text != "" {
+		t.Fatalf("%v is not ranked by the total", scored)
+	}
+
+	embedded := EmbedResponse{}
+	post("/embed", `{"text": "abc"}`, &embedded)
+	if len(embedded.Embedding) != InputSize {
+		t.Fatalf("%d is not %d", len(embedded.Embedding), InputSize)
+	}
+
+	stream := func(body string) ([]StreamEvent, GenerateResponse) {
+		r, err := http.Post(s.URL+"/generate", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+		if r.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("%s is not an event stream", r.Header.Get("Content-Type"))
+		}
+		events, done, scanner := []StreamEvent{}, GenerateResponse{}, bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "event: done" {
+				scanner.Scan()
+				err := json.Unmarshal([]byte(strings.TrimPrefix(scanner.Text(), "data: ")), &done)
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if data, ok := strings.CutPrefix(line, "data: "); ok {
+				event := StreamEvent{}
+				err := json.Unmarshal([]byte(data), &event)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if event.Index != len(events) {
+					t.Fatalf("%v", event)
+				}
+				events = append(events, event)
+			}
+		}
+		return events, done
+	}
+	for _, test := range []struct {
+		Body   string
+		Text   string
+		Finish Finish
+	}{
+		{`{"length": 3, "stream": true}`, "bbb", FinishLength},
+		{`{"stop": ["bb"], "stream": true}`, "", FinishStop},
+		{`{"stop": ["bc"], "stream": true}`, "bbbb", FinishLength},
+		{`{"constraint": "ca", "stream": true}`, "ca", FinishMatch},
+	} {
+		events, done := stream(test.Body)
+		streamed := ""
+		for _, event := range events {
+			streamed += event.Text
+		}
+		if streamed != test.Text || done.Text != test.Text || done.Finish != test.Finish {
+			t.Fatalf("%s streamed %q %v", test.Body, streamed, done)
+		}
+	}
+
+	counted, calls := model, 0
+	counted.Distribution = func(m Mix) []float32 {
+		calls++
+		return model.Distribution(m)
+	}
+	canceled := NewServer(counted, NewSampler(0, 0, 1, 4, 1, 1), NewText(Stop{}, reverse), 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, body := range []string{`{"length": 4000}`, `{"length": 4000, "stream": true}`} {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/generate", strings.NewReader(body)).WithContext(ctx)
+		canceled.Handler().ServeHTTP(recorder, request)
+		if calls != 0 || strings.Contains(recorder.Body.String(), "text") {
+			t.Fatalf("%s generated %d symbols after it was canceled %q", body, calls, recorder.Body.String())
+		}
+	}
+	if len(canceled.Limit) != 0 {
+		t.Fatal("a canceled request kept its slot")
+	}
+
+	server.Limit <- struct{}{}
+	server.Limit <- struct{}{}
+	if status := post("/embed", `{"text": "a"}`, &failed); status != http.StatusServiceUnavailable {
+		t.Fatalf("status %d", status)
+	}
+}
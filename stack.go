@@ -0,0 +1,32 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+)
+
+// NewStackWithMeta makes the fused or concatenated stack of mixers named in the metadata of a database,
+// classes are the character classes of the symbols used by the word features
+func NewStackWithMeta(meta Meta, classes []byte) *Stack {
+	mixers := make([]Mix, len(meta.Stack))
+	for i, name := range meta.Stack {
+		switch name {
+		case "filtered":
+			mixers[i] = NewFilteredWithMeta(meta)
+		case "mixer":
+			mixers[i] = NewMixerWithConfig(256, meta.Mixer)
+		case "basic":
+			mixers[i] = NewBasicWithConfig(256, meta.Mixer)
+		case "decayed":
+			mixers[i] = NewDecayedWithConfig(256, meta.Mixer)
+		case "words":
+			mixers[i] = NewWords(classes)
+		default:
+			panic(fmt.Errorf("unknown mixer %s", name))
+		}
+	}
+	return NewStack(meta.Mixer.Order, meta.Concat, mixers...)
+}
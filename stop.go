@@ -0,0 +1,291 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Finish is the reason generation finished
+type Finish string
+
+const (
+	// FinishLength is the finish reason when the maximum length is reached
+	FinishLength Finish = "length"
+	// FinishStop is the finish reason when a stop string is generated
+	FinishStop Finish = "stop"
+	// FinishLine is the finish reason when the end of a line is generated
+	FinishLine Finish = "line"
+	// FinishParagraph is the finish reason when the end of a paragraph is generated
+	FinishParagraph Finish = "paragraph"
+	// FinishMatch is the finish reason when the constraint is matched and can't be extended
+	FinishMatch Finish = "match"
+	// FinishDead is the finish reason when the constraint can't be matched
+	FinishDead Finish = "dead"
+	// FinishCanceled is the finish reason when the context of the text is done
+	FinishCanceled Finish = "canceled"
+)
+
+// Strings is a flag that can be repeated
+type Strings []string
+
+// String is the flag value
+func (s *Strings) String() string {
+	return strings.Join(*s, ",")
+}
+
+// Set adds a value with go escape sequences to the flag
+func (s *Strings) Set(value string) error {
+	if unquoted, err := strconv.Unquote(`"` + value + `"`); err == nil {
+		value = unquoted
+	}
+	*s = append(*s, value)
+	return nil
+}
+
+// Stop are the conditions that finish generation before the maximum length
+type Stop struct {
+	// Strings are the stop strings
+	Strings []string
+	// Line finishes at the end of a line
+	Line bool
+	// Paragraph finishes at the end of a paragraph
+	Paragraph bool
+}
+
+// FlagStop is the stop conditions selected by the flags
+func FlagStop() Stop {
+	return Stop{
+		Strings:   FlagStops,
+		Line:      *FlagLine,
+		Paragraph: *FlagParagraph,
+	}
+}
+
+// trim removes a trailing carriage return
+func trim(text []rune, n int) int {
+	if n > 0 && text[n-1] == '\r' {
+		n--
+	}
+	return n
+}
+
+// Check checks if text finishes with a stop condition, the length of the text without the stop is returned
+func (s Stop) Check(text []rune) (int, Finish) {
+	n := len(text)
+	for _, stop := range s.Strings {
+		r := []rune(stop)
+		if len(r) == 0 || len(r) > n {
+			continue
+		}
+		if string(text[n-len(r):]) == stop {
+			return n - len(r), FinishStop
+		}
+	}
+	if n == 0 || text[n-1] != '\n' {
+		return n, ""
+	}
+	if s.Line {
+		return trim(text, n-1), FinishLine
+	}
+	if s.Paragraph {
+		for i := n - 2; i >= 0; i-- {
+			if text[i] == '\n' {
+				return trim(text, i), FinishParagraph
+			}
+			if !unicode.IsSpace(text[i]) {
+				break
+			}
+		}
+	}
+	return n, ""
+}
+
+// Pending is the number of runes at the end of text that could be the start of a stop condition
+func (s Stop) Pending(text []rune) int {
+	n, pending := len(text), 0
+	for _, stop := range s.Strings {
+		r := []rune(stop)
+		for k := min(len(r)-1, n); k > pending; k-- {
+			if string(text[n-k:]) == string(r[:k]) {
+				pending = k
+				break
+			}
+		}
+	}
+	if (s.Line || s.Paragraph) && n > 0 && text[n-1] == '\r' {
+		pending = max(pending, 1)
+	}
+	if s.Paragraph {
+		for i := n - 1; i >= 0; i-- {
+			if text[i] == '\n' {
+				pending = max(pending, n-trim(text, i))
+				break
+			}
+			if !unicode.IsSpace(text[i]) {
+				break
+			}
+		}
+	}
+	return pending
+}
+
+// Text is generated text that is checked for the stop conditions
+type Text struct {
+	Stop       Stop
+	Reverse    map[byte]rune
+	Symbols    []byte
+	Runes      []rune
+	Finish     Finish
+	Penalty    *Penalty
+	Constraint *Constraint
+	// Stream is called with the index and rune of each symbol of the text once the symbol can't be removed by a stop
+	Stream func(index int, r rune)
+	// Context cancels generation when it is done, nil never cancels
+	Context  context.Context
+	streamed int
+}
+
+// NewText makes new generated text, symbols are runes if reverse is nil
+func NewText(stop Stop, reverse map[byte]rune) *Text {
+	return &Text{
+		Stop:    stop,
+		Reverse: reverse,
+		Finish:  FinishLength,
+	}
+}
+
+// FlagText makes new generated text with the stop conditions, penalty and constraint selected by the flags
+func FlagText(reverse map[byte]rune) *Text {
+	text := NewText(FlagStop(), reverse)
+	text.Penalty = FlagPenalty()
+	text.Constraint = FlagConstraint(reverse)
+	return text
+}
+
+// Copy copies the text
+func (t *Text) Copy() *Text {
+	cp := *t
+	cp.Symbols, cp.Runes = slices.Clone(t.Symbols), slices.Clone(t.Runes)
+	if t.Penalty != nil {
+		cp.Penalty = t.Penalty.Copy()
+	}
+	if t.Constraint != nil {
+		cp.Constraint = t.Constraint.Copy()
+	}
+	return &cp
+}
+
+// Canceled is true if the context of the text is done
+func (t *Text) Canceled() bool {
+	return t.Context != nil && t.Context.Err() != nil
+}
+
+// Continued is a copy of the text for generating after it, the penalty and the constraint keep their state
+func (t *Text) Continued() *Text {
+	cp := t.Copy()
+	cp.Symbols, cp.Runes, cp.Finish, cp.streamed = nil, nil, FinishLength, 0
+	return cp
+}
+
+// Reversed is a copy of a new text for generating reversed text, the stop strings and the constraint are reversed
+func (t *Text) Reversed() *Text {
+	cp := t.Copy()
+	cp.Stop.Strings = make([]string, len(t.Stop.Strings))
+	for i, stop := range t.Stop.Strings {
+		r := []rune(stop)
+		slices.Reverse(r)
+		cp.Stop.Strings[i] = string(r)
+	}
+	if t.Constraint != nil {
+		cp.Constraint = NewConstraint(t.Constraint.DFA.Reversed())
+	}
+	return cp
+}
+
+// Apply applies the penalty and the constraint of the text to a distribution
+func (t *Text) Apply(distribution []float32) []float32 {
+	if t.Penalty != nil {
+		distribution = t.Penalty.Apply(distribution)
+	}
+	if t.Constraint != nil {
+		distribution = t.Constraint.Mask(distribution)
+	}
+	return distribution
+}
+
+// Add adds a symbol to the text returning true if generation is finished, the stop is removed from the text
+// and a symbol that can't match the constraint isn't added
+func (t *Text) Add(symbol byte) bool {
+	if t.Constraint != nil && !t.Constraint.Add(symbol) {
+		t.Finish = FinishDead
+		t.Flush()
+		return true
+	}
+	r := rune(symbol)
+	if t.Reverse != nil {
+		r = t.Reverse[symbol]
+	}
+	if t.Penalty != nil {
+		t.Penalty.Add(symbol)
+	}
+	t.Symbols = append(t.Symbols, symbol)
+	t.Runes = append(t.Runes, r)
+	n, finish := t.Stop.Check(t.Runes)
+	if finish == "" {
+		if t.Constraint != nil && t.Constraint.Complete() {
+			t.Finish = FinishMatch
+			t.Flush()
+			return true
+		}
+		t.stream(len(t.Runes) - t.Stop.Pending(t.Runes))
+		return false
+	}
+	t.Symbols, t.Runes, t.Finish = t.Symbols[:n], t.Runes[:n], finish
+	t.Flush()
+	return true
+}
+
+// stream streams the runes of the text before end
+func (t *Text) stream(end int) {
+	if t.Stream == nil {
+		return
+	}
+	for ; t.streamed < end; t.streamed++ {
+		t.Stream(t.streamed, t.Runes[t.streamed])
+	}
+}
+
+// Flush streams the runes that are held back because they could be the start of a stop condition
+func (t *Text) Flush() {
+	t.stream(len(t.Runes))
+}
+
+// AddAll adds symbols to the text until generation is finished
+func (t *Text) AddAll(symbols []byte) *Text {
+	for _, symbol := range symbols {
+		if t.Add(symbol) {
+			break
+		}
+	}
+	return t
+}
+
+// String is the generated text
+func (t *Text) String() string {
+	return string(t.Runes)
+}
+
+// Print prints the generated text and reports the finish reason on standard error
+func (t *Text) Print() {
+	fmt.Println(t.String())
+	fmt.Fprintln(os.Stderr, "finish", t.Finish)
+}
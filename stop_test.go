@@ -0,0 +1,70 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestStop(t *testing.T) {
+	var stops Strings
+	for _, value := range []string{"END", `\n\n`} {
+		err := stops.Set(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stops[1] != "\n\n" {
+		t.Fatalf("%q is not unescaped", stops[1])
+	}
+
+	for text, pending := range map[string]int{"the E": 1, "the EN": 2, "the end": 0, "line\r": 1, "one\r\n ": 3, "one\n\tx": 0} {
+		if p := (Stop{Strings: []string{"END"}, Paragraph: true}).Pending([]rune(text)); p != pending {
+			t.Fatalf("%q: %d != %d", text, p, pending)
+		}
+	}
+
+	tests := []struct {
+		Stop   Stop
+		Input  string
+		Output string
+		Finish Finish
+	}{
+		{Stop{Strings: []string{"END"}}, "the end. END of it", "the end. ", FinishStop},
+		{Stop{Line: true}, "first line\r\nsecond", "first line", FinishLine},
+		{Stop{Paragraph: true}, "one\r\ntwo\r\n \r\nthree", "one\r\ntwo", FinishParagraph},
+		{Stop{Paragraph: true}, "one\r\ntwo", "one\r\ntwo", FinishLength},
+		{Stop{}, "one\r\n\r\ntwo", "one\r\n\r\ntwo", FinishLength},
+		{Stop{Strings: []string{"END", "EX"}}, "the EN", "the EN", FinishLength},
+		{Stop{Paragraph: true}, "one\r\n \t", "one\r\n \t", FinishLength},
+	}
+	for _, test := range tests {
+		text, streamed := NewText(test.Stop, nil), []rune{}
+		text.Stream = func(index int, r rune) {
+			if index != len(streamed) {
+				t.Fatalf("%q: %d is not the index %d", test.Input, index, len(streamed))
+			}
+			streamed = append(streamed, r)
+		}
+		for _, s := range []byte(test.Input) {
+			if text.Add(s) {
+				break
+			}
+			if pending := len(text.Runes) - len(streamed); pending != test.Stop.Pending(text.Runes) {
+				t.Fatalf("%q: %d runes are held back", test.Input, pending)
+			}
+		}
+		text.Flush()
+		if text.String() != test.Output || text.Finish != test.Finish {
+			t.Fatalf("%q: %q %s != %q %s", test.Input, text.String(), text.Finish, test.Output, test.Finish)
+		}
+		if string(streamed) != test.Output {
+			t.Fatalf("%q: %q is streamed", test.Input, string(streamed))
+		}
+		if len(text.Symbols) != len(text.Runes) {
+			t.Fatalf("%d != %d", len(text.Symbols), len(text.Runes))
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"index/suffixarray"
+	"math"
+	"slices"
+)
+
+const (
+	// SuffixMax is the maximum length of an exact match
+	SuffixMax = 64
+	// SuffixLimit is the maximum number of occurrences of a match that are counted
+	SuffixLimit = 4096
+	// SuffixScale is the match length of a confidence of 1-1/e
+	SuffixScale = 8
+)
+
+// Suffix is a suffix array over the encoded corpus
+type Suffix struct {
+	Symbols []byte
+	// Alphabet is the number of symbols in the alphabet
+	Alphabet int
+	Index    *suffixarray.Index
+}
+
+// NewSuffix makes a new suffix array over the symbols of the corpus
+func NewSuffix(symbols []byte, alphabet int) *Suffix {
+	return &Suffix{
+		Symbols:  symbols,
+		Alphabet: alphabet,
+		Index:    suffixarray.New(symbols),
+	}
+}
+
+// Match is the longest exact match of the end of a context in the corpus
+type Match struct {
+	// Length is the length of the match
+	Length int
+	// Distribution is the distribution of the symbols that follow the match
+	Distribution []float32
+}
+
+// Match finds the longest exact match of the end of the context that is followed by a symbol
+func (s *Suffix) Match(context []byte) Match {
+	match := Match{Distribution: make([]float32, s.Alphabet)}
+	n := len(context)
+	for match.Length < min(n, SuffixMax) {
+		if len(s.Index.Lookup(context[n-match.Length-1:], 1)) == 0 {
+			break
+		}
+		match.Length++
+	}
+	for match.Length > 0 {
+		sum := float32(0.0)
+		for _, p := range s.Index.Lookup(context[n-match.Length:], SuffixLimit) {
+			if next := p + match.Length; next < len(s.Symbols) {
+				match.Distribution[s.Symbols[next]]++
+				sum++
+			}
+		}
+		if sum > 0 {
+			for i := range match.Distribution {
+				match.Distribution[i] /= sum
+			}
+			break
+		}
+		match.Length--
+	}
+	return match
+}
+
+// Confidence is the confidence of the match from its length
+func (m Match) Confidence() float64 {
+	return 1 - math.Exp(-float64(m.Length)/SuffixScale)
+}
+
+// Blend blends the distribution of the match into a distribution weighted by the confidence
+func (m Match) Blend(distribution []float32) []float32 {
+	c := float32(m.Confidence())
+	output := make([]float32, len(distribution))
+	for i, v := range distribution {
+		output[i] = (1-c)*v + c*m.Distribution[i]
+	}
+	return output
+}
+
+// Confident is a mixer with a confidence in its next symbol
+type Confident interface {
+	Confidence() float64
+}
+
+// confidence is the confidence of a mixer, 0 if the mixer isn't confident
+func confidence(m Mix) float64 {
+	if c, ok := m.(Confident); ok {
+		return c.Confidence()
+	}
+	return 0
+}
+
+// Suffixed is a mixer that matches the end of its context in a suffix array
+type Suffixed struct {
+	Mixer   Mix
+	Suffix  *Suffix
+	Context []byte
+	match   *Match
+}
+
+// NewSuffixed makes a new suffixed mixer, the mixer has been run over the context
+func NewSuffixed(m Mix, suffix *Suffix, context []byte) *Suffixed {
+	return &Suffixed{
+		Mixer:   m,
+		Suffix:  suffix,
+		Context: slices.Clone(context[max(0, len(context)-SuffixMax):]),
+	}
+}
+
+// Copy copies the mixer
+func (s *Suffixed) Copy() Mix {
+	return &Suffixed{
+		Mixer:   s.Mixer.Copy(),
+		Suffix:  s.Suffix,
+		Context: slices.Clone(s.Context),
+		match:   s.match,
+	}
+}
+
+// Add adds a symbol to the mixer
+func (s *Suffixed) Add(symbol byte) {
+	s.Mixer.Add(symbol)
+	s.Context = append(s.Context, symbol)
+	if len(s.Context) > SuffixMax {
+		s.Context = slices.Clone(s.Context[1:])
+	}
+	s.match = nil
+}
+
+// Mix mixes the histograms
+func (s *Suffixed) Mix() []float32 {
+	return s.Mixer.Mix()
+}
+
+// Match is the longest exact match of the end of the context
+func (s *Suffixed) Match() Match {
+	if s.match == nil {
+		match := s.Suffix.Match(s.Context)
+		s.match = &match
+	}
+	return *s.match
+}
+
+// Confidence is the confidence of the match
+func (s *Suffixed) Confidence() float64 {
+	return s.Match().Confidence()
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 The Textus Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestSuffix(t *testing.T) {
+	suffix := NewSuffix([]byte("the cat sat. the cat ran. the dog sat."), 256)
+
+	match := suffix.Match([]byte("a big cat "))
+	if match.Length != 5 || match.Distribution['s'] != .5 || match.Distribution['r'] != .5 {
+		t.Fatalf("%d %v", match.Length, match.Distribution['r':'t'])
+	}
+	if match := suffix.Match([]byte("xyz")); match.Length != 0 || match.Confidence() != 0 {
+		t.Fatalf("%d is not 0", match.Length)
+	}
+	if match := suffix.Match([]byte("dog sat.")); match.Length != 5 || match.Distribution[' '] != 1 {
+		t.Fatalf("%d should not match the end of the corpus", match.Length)
+	}
+	if long := suffix.Match([]byte("the cat ")); long.Confidence() <= match.Confidence() {
+		t.Fatalf("%f <= %f", long.Confidence(), match.Confidence())
+	}
+
+	distribution := make([]float32, 256)
+	distribution['x'] = 1
+	blended := match.Blend(distribution)
+	if c := float32(match.Confidence()); blended['x'] != 1-c || blended['s'] != c/2 {
+		t.Fatalf("%f %f", blended['x'], blended['s'])
+	}
+
+	s := NewSuffixed(NewFiltered(), suffix, []byte("the c"))
+	cp := s.Copy().(*Suffixed)
+	cp.Add('a')
+	if s.Match().Length != 5 || cp.Match().Length != 6 {
+		t.Fatalf("%d %d", s.Match().Length, cp.Match().Length)
+	}
+
+	if c := (&Copying{Mixer: s}).Confidence(); c == 0 || c != s.Confidence() {
+		t.Fatalf("%f is not the confidence of the copied mixer", c)
+	}
+	if c := (&Tally{Mixer: s}).Confidence(); c == 0 || c != s.Confidence() {
+		t.Fatalf("%f is not the confidence of the tallied mixer", c)
+	}
+	suffixed := 0
+	exact := func(m Mix) ([]float32, []Neighbor) {
+		if s, ok := m.(*Suffixed); ok {
+			suffixed++
+			return s.Match().Blend(distribution), nil
+		}
+		return distribution, nil
+	}
+	NewCopier(nil, 256, 1, 1).Generate(NewSampler(0, 0, 1, 2, 1, 1), s.Copy(), exact, NewText(Stop{}, nil))
+	if suffixed != 2 {
+		t.Fatalf("the copier blended %d distributions", suffixed)
+	}
+
+	if sampler := NewSampler(1, 0, 1, 1, 1, 1).Confident(.75); sampler.Temperature != .25 {
+		t.Fatalf("%f is not .25", sampler.Temperature)
+	}
+}
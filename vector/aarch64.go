@@ -12,6 +12,10 @@ import (
 )
 
 func Dot(x, y []float32) (z float32) {
+	// the assembly does not initialize its accumulator for less than 4 elements
+	if len(x) < 4 {
+		return dot(x, y)
+	}
 	vdot(unsafe.Pointer(&x[0]), unsafe.Pointer(&y[0]), unsafe.Pointer(uintptr(len(x))), unsafe.Pointer(&z))
 	return z
 }
@@ -12,6 +12,10 @@ import (
 )
 
 func Dot(x, y []float32) (z float32) {
+	// the assembly does not initialize its accumulator for less than 8 elements
+	if len(x) < 8 {
+		return dot(x, y)
+	}
 	_mm256_dot(unsafe.Pointer(&x[0]), unsafe.Pointer(&y[0]), unsafe.Pointer(uintptr(len(x))), unsafe.Pointer(&z))
 	return z
 }
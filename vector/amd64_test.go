@@ -28,6 +28,13 @@ func TestDot(t *testing.T) {
 	}
 }
 
+func TestDotShort(t *testing.T) {
+	x, y := []float32{1, 2, 3}, []float32{4, 5, 6}
+	if a := Dot(x, y); a != 32 {
+		t.Fatalf("dot product is broken %f != 32", a)
+	}
+}
+
 func BenchmarkVectorDot(b *testing.B) {
 	rng := rand.New(rand.NewSource(1))
 	x := make([]float32, Size)